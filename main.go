@@ -0,0 +1,7 @@
+package main
+
+import "github.com/hyperglue/oko-press-rss/okorss"
+
+func main() {
+	okorss.Main()
+}
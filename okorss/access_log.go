@@ -0,0 +1,56 @@
+package okorss
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Remembers status code and size of the response for the access log
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	written, err := recorder.ResponseWriter.Write(data)
+	recorder.size += written
+	return written, err
+}
+
+// WebSocket upgrades take over the connection
+func (recorder *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	recorder.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(recorder.ResponseWriter).Hijack()
+}
+
+// For http.ResponseController, e.g. long polls extending their deadline
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// Log every request with the real client address when access_log is set
+func accessLog(next http.Handler) http.Handler {
+
+	if !config.AccessLog {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		slog.Info("Request", "client", clientIP(r), "method", r.Method, "path", r.URL.RequestURI(), "status", recorder.status, "size", recorder.size, "duration", time.Since(start))
+	})
+}
@@ -0,0 +1,149 @@
+package okorss
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operator alerts about the refresh itself, as opposed to notifications
+// about articles
+type AlertConfig struct {
+	Threshold int              `json:"threshold"`
+	Webhook   WebhookConfig    `json:"webhook"`
+	Ntfy      NtfyConfig       `json:"ntfy"`
+	Email     AlertEmailConfig `json:"email"`
+}
+
+type AlertEmailConfig struct {
+	Enabled bool `json:"enabled"`
+	SmtpConfig
+}
+
+type AlertPayload struct {
+	Event               string    `json:"event"`
+	Message             string    `json:"message"`
+	Error               string    `json:"error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Feed                string    `json:"feed"`
+	Time                time.Time `json:"time"`
+}
+
+// Whether a failure alert went out and wasn't followed by a recovery yet
+var alertState struct {
+	sync.Mutex
+	alerting bool
+	failures int
+}
+
+func alertThreshold() int {
+	if config.Alerts.Threshold > 0 {
+		return config.Alerts.Threshold
+	}
+	return 3
+}
+
+// Alert once when refreshes keep failing, and once more when they recover
+func alertOnFailures(err error, failures int) {
+
+	alertState.Lock()
+	defer alertState.Unlock()
+
+	var payload AlertPayload
+	if err != nil {
+		alertState.failures = failures
+		if alertState.alerting || failures < alertThreshold() {
+			return
+		}
+		alertState.alerting = true
+		payload = AlertPayload{
+			Event:   "refresh_failing",
+			Message: fmt.Sprintf("Feed refresh failed %d times in a row", failures),
+			Error:   err.Error(),
+		}
+	} else {
+		if !alertState.alerting {
+			return
+		}
+		alertState.alerting = false
+		payload = AlertPayload{
+			Event:   "refresh_recovered",
+			Message: fmt.Sprintf("Feed refresh recovered after %d failures", alertState.failures),
+		}
+	}
+	payload.ConsecutiveFailures = failures
+	payload.Feed = config.Url
+	payload.Time = time.Now()
+
+	// Don't hold up the refresh
+	go sendAlert(payload)
+}
+
+func sendAlert(payload AlertPayload) {
+
+	slog.Warn("Sending alert", "event", payload.Event, "message", payload.Message)
+	alerts := config.Alerts
+
+	if alerts.Webhook.Url != "" {
+		err := alerts.Webhook.post(payload)
+		if err != nil {
+			slog.Error("Error while sending alert", "channel", "webhook", "error", err)
+		}
+	}
+
+	if alerts.Ntfy.Enabled {
+		err := sendNtfyAlert(alerts.Ntfy, payload)
+		if err != nil {
+			slog.Error("Error while sending alert", "channel", "ntfy", "error", err)
+		}
+	}
+
+	if alerts.Email.Enabled {
+		subject := "oko-rss: " + payload.Message
+		message := mailMessage(alerts.Email.SmtpConfig, subject, "text/plain", []byte(alertText(payload)))
+		err := sendMail(alerts.Email.SmtpConfig, message)
+		if err != nil {
+			slog.Error("Error while sending alert", "channel", "email", "error", err)
+		}
+	}
+}
+
+func alertText(payload AlertPayload) string {
+	lines := []string{payload.Message + ".", "", "Feed: " + payload.Feed}
+	if payload.Error != "" {
+		lines = append(lines, "Last error: "+payload.Error)
+	}
+	lines = append(lines, "Time: "+payload.Time.Format(time.RFC1123Z))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func sendNtfyAlert(ntfy NtfyConfig, payload AlertPayload) error {
+
+	server := strings.TrimSuffix(ntfy.Server, "/")
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	headers := make(map[string]string)
+	if ntfy.Token != "" {
+		headers["Authorization"] = "Bearer " + ntfy.Token
+	}
+
+	message := map[string]interface{}{
+		"topic":   ntfy.Topic,
+		"title":   "oko-rss: " + payload.Message,
+		"message": alertText(payload),
+		"tags":    []string{"warning"},
+	}
+	if payload.Event == "refresh_recovered" {
+		message["tags"] = []string{"white_check_mark"}
+	}
+	if ntfy.Priority > 0 {
+		message["priority"] = ntfy.Priority
+	}
+
+	return retry(3, 5*time.Second, func() error {
+		return sendJson("POST", server, headers, message, nil)
+	})
+}
@@ -0,0 +1,380 @@
+package okorss
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Schema changes are applied in order and tracked with PRAGMA user_version,
+// so new migrations must only ever be appended to this list
+var archiveMigrations = []string{
+	`CREATE TABLE articles (
+		id TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		slug TEXT NOT NULL,
+		published TEXT NOT NULL,
+		image TEXT NOT NULL,
+		raw TEXT NOT NULL,
+		first_seen TEXT NOT NULL,
+		last_seen TEXT NOT NULL
+	);
+	CREATE INDEX articles_published ON articles (published);`,
+	`ALTER TABLE articles ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE articles ADD COLUMN updated TEXT NOT NULL DEFAULT '';`,
+	`CREATE TABLE meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`,
+	`CREATE TABLE fetches (
+		time TEXT NOT NULL,
+		ok INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		items INTEGER NOT NULL
+	);
+	CREATE INDEX fetches_time ON fetches (time);`,
+	`ALTER TABLE articles ADD COLUMN node TEXT NOT NULL DEFAULT '';`,
+}
+
+// Fetch log is only needed for recent statistics
+const fetchLogRetention = 30 * 24 * time.Hour
+
+// Global archive handle, nil when archiving is disabled
+var archive *sql.DB
+
+func openArchive(path string) (*sql.DB, error) {
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows only a single writer, avoid "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	// Bring schema up to date
+	var version int
+	err = db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for i := version; i < len(archiveMigrations); i++ {
+		_, err = db.Exec(archiveMigrations[i])
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// Open the configured archive for commands that can't work without one
+func openConfiguredArchive() bool {
+
+	if config.Archive == "" {
+		fmt.Printf("This command needs an archive, please set \"archive\" in config!")
+		return false
+	}
+
+	var err error
+	archive, err = openArchive(config.Archive)
+	if err != nil {
+		fatal("Error while opening archive", "error", err)
+	}
+	return true
+}
+
+// Hash of the fields readers care about, used to notice edited articles
+func contentHash(node Node) string {
+	hash := sha256.New()
+	for _, field := range []string{node.Title, node.SeoFields.Slug, node.Lead} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Insert new articles and refresh the ones seen before. Articles whose
+// content changed since the last fetch get their updated time bumped,
+// which is copied back into the given nodes. Returns IDs of articles
+// which were added or updated, mapped to ChangeNew or ChangeUpdated, nil
+// when the archive was empty before
+func archiveNodes(db *sql.DB, nodes []Node) (map[string]string, error) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A new archive knows nothing yet, everything in it would look new
+	var empty bool
+	err = tx.QueryRow("SELECT NOT EXISTS (SELECT 1 FROM articles)").Scan(&empty)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, err := tx.Prepare(`INSERT INTO articles (id, title, slug, published, image, raw, node, first_seen, last_seen, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			title = excluded.title,
+			slug = excluded.slug,
+			published = excluded.published,
+			image = excluded.image,
+			raw = excluded.raw,
+			node = excluded.node,
+			last_seen = excluded.last_seen,
+			hash = excluded.hash,
+			updated = CASE WHEN articles.hash NOT IN ('', excluded.hash) THEN excluded.last_seen ELSE articles.updated END
+		RETURNING first_seen, updated`)
+	if err != nil {
+		return nil, err
+	}
+	defer statement.Close()
+
+	changes := make(map[string]string)
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, node := range nodes {
+		raw := node.Raw
+		if raw == nil {
+			raw, err = json.Marshal(node)
+			if err != nil {
+				return nil, err
+			}
+		}
+		normalized, err := json.Marshal(node)
+		if err != nil {
+			return nil, err
+		}
+		var firstSeen, updated string
+		err = statement.QueryRow(node.ID, node.Title, node.SeoFields.Slug, node.Published, node.Image.Url, string(raw), string(normalized), now, now, contentHash(node)).Scan(&firstSeen, &updated)
+		if err != nil {
+			return nil, fmt.Errorf("article %s: %w", node.ID, err)
+		}
+		nodes[i].Updated = parseArchiveTime(updated)
+
+		if firstSeen == now {
+			changes[node.ID] = ChangeNew
+		} else if updated == now {
+			changes[node.ID] = ChangeUpdated
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil || empty {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Get the most recently published archived articles, skipping given IDs
+func recentArchivedNodes(db *sql.DB, limit int, skip map[string]bool) ([]Node, error) {
+
+	rows, err := db.Query("SELECT id, raw, node, published, updated FROM articles ORDER BY published DESC, id DESC LIMIT ?", limit+len(skip))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []Node
+	for len(nodes) < limit && rows.Next() {
+		var id, raw, stored, published, updated string
+		err = rows.Scan(&id, &raw, &stored, &published, &updated)
+		if err != nil {
+			return nil, err
+		}
+		if skip[id] {
+			continue
+		}
+
+		node, err := archivedNode(id, raw, stored, published, updated)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, rows.Err()
+}
+
+// Article as fetched, from the node stored alongside the upstream JSON so
+// fallback dates, source labels and prefixed IDs survive. Rows archived
+// before it was stored are decoded from the upstream JSON, with the title
+// cleaned up as on fetch and the publish time from its column
+func archivedNode(id string, raw string, stored string, published string, updated string) (Node, error) {
+
+	var node Node
+	data := stored
+	if data == "" {
+		data = raw
+	}
+	err := json.Unmarshal([]byte(data), &node)
+	if err != nil {
+		return node, fmt.Errorf("article %s: %w", id, err)
+	}
+	if stored == "" {
+		node.Title = plainText(node.Title)
+	}
+	node.Raw = json.RawMessage(raw)
+	if published != "" {
+		node.Published = published
+	}
+	node.Updated = parseArchiveTime(updated)
+	return node, nil
+}
+
+// Timestamps are stored as RFC 3339 text, empty meaning not set
+func parseArchiveTime(value string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339, value)
+	return parsed
+}
+
+// Small key-value store for bookkeeping, e.g. backfill progress
+func archiveMeta(db *sql.DB, key string) (string, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func setArchiveMeta(db *sql.DB, key string, value string) error {
+	_, err := db.Exec("INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, value)
+	return err
+}
+
+// Article as stored in the archive, along with bookkeeping times
+type ArchivedArticle struct {
+	Node
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Get archived articles published in given range, newest first. Bounds use
+// the API time format and are skipped when empty
+func archivedArticles(db *sql.DB, since string, until string) ([]ArchivedArticle, error) {
+
+	rows, err := db.Query(`SELECT id, raw, node, published, first_seen, last_seen, updated FROM articles
+		WHERE (? = '' OR published >= ?) AND (? = '' OR published < ?)
+		ORDER BY published DESC, id DESC`, since, since, until, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []ArchivedArticle
+	for rows.Next() {
+		var id, raw, stored, published, firstSeen, lastSeen, updated string
+		err = rows.Scan(&id, &raw, &stored, &published, &firstSeen, &lastSeen, &updated)
+		if err != nil {
+			return nil, err
+		}
+
+		var article ArchivedArticle
+		article.Node, err = archivedNode(id, raw, stored, published, updated)
+		if err != nil {
+			return nil, err
+		}
+		article.FirstSeen = parseArchiveTime(firstSeen)
+		article.LastSeen = parseArchiveTime(lastSeen)
+		articles = append(articles, article)
+	}
+
+	return articles, rows.Err()
+}
+
+// Delete articles beyond the retention limits, zero meaning no limit.
+// Articles still returned by the API are never pruned by age
+func pruneArchive(db *sql.DB, keepDays int, keepItems int) (int64, error) {
+
+	var pruned int64
+
+	_, err := db.Exec("DELETE FROM fetches WHERE time < ?", time.Now().UTC().Add(-fetchLogRetention).Format(time.RFC3339))
+	if err != nil {
+		return pruned, err
+	}
+
+	if keepDays > 0 {
+		cutoff := time.Now().UTC().AddDate(0, 0, -keepDays)
+		result, err := db.Exec("DELETE FROM articles WHERE published < ? AND last_seen < ?",
+			cutoff.Format("2006-01-02T15:04:05"), cutoff.Format(time.RFC3339))
+		if err != nil {
+			return pruned, err
+		}
+		count, _ := result.RowsAffected()
+		pruned += count
+	}
+
+	if keepItems > 0 {
+		result, err := db.Exec(`DELETE FROM articles WHERE id NOT IN (
+			SELECT id FROM articles ORDER BY published DESC, id DESC LIMIT ?)`, keepItems)
+		if err != nil {
+			return pruned, err
+		}
+		count, _ := result.RowsAffected()
+		pruned += count
+	}
+
+	return pruned, nil
+}
+
+// Get one page of archived articles, newest first, and the total count
+func archivePage(db *sql.DB, offset int, limit int) ([]Node, int, error) {
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM articles").Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query("SELECT id, raw, node, published, updated FROM articles ORDER BY published DESC, id DESC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var nodes []Node
+	for rows.Next() {
+		var id, raw, stored, published, updated string
+		err = rows.Scan(&id, &raw, &stored, &published, &updated)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		node, err := archivedNode(id, raw, stored, published, updated)
+		if err != nil {
+			return nil, 0, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, total, rows.Err()
+}
+
+// Log outcome of an upstream fetch
+func recordFetch(db *sql.DB, start time.Time, items int, fetchErr error) error {
+
+	ok := fetchErr == nil
+	message := ""
+	if !ok {
+		message = fetchErr.Error()
+	}
+
+	_, err := db.Exec("INSERT INTO fetches (time, ok, error, duration_ms, items) VALUES (?, ?, ?, ?, ?)",
+		start.UTC().Format(time.RFC3339), ok, message, time.Since(start).Milliseconds(), items)
+	return err
+}
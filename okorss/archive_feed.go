@@ -0,0 +1,78 @@
+package okorss
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// Page size limits for /archive.xml
+const (
+	archiveFeedDefaultLimit = 50
+	archiveFeedMaxLimit     = 500
+)
+
+// Read a positive integer query parameter, falling back to default
+func queryInt(r *http.Request, name string, fallback int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
+
+// Full history feed, split into pages linked as described in RFC 5005
+// section 3. Use ?page=N to move between pages and ?limit=M for page size
+func serveArchiveRss(w http.ResponseWriter, r *http.Request) {
+
+	limit := queryInt(r, "limit", archiveFeedDefaultLimit)
+	if limit > archiveFeedMaxLimit {
+		limit = archiveFeedMaxLimit
+	}
+	page := queryInt(r, "page", 1)
+
+	nodes, total, err := archivePage(archive, (page-1)*limit, limit)
+	if err != nil {
+		slog.Error("Error while reading archive", "path", r.URL.Path, "error", err)
+		http.Error(w, "archive unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	lastPage := (total + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	if page > lastPage {
+		http.NotFound(w, r)
+		return
+	}
+
+	pageUrl := func(number int) string {
+		url := requestBaseUrl(r) + r.URL.Path + "?page=" + strconv.Itoa(number)
+		if limit != archiveFeedDefaultLimit {
+			url += "&limit=" + strconv.Itoa(limit)
+		}
+		return url
+	}
+
+	rss := buildRss(nodes, itemPipeline)
+	channel := &rss.Channel
+	channel.Title += " (archiwum)"
+	channel.AtomLink = []AtomLink{
+		{Rel: "self", Href: pageUrl(page)},
+		{Rel: "first", Href: pageUrl(1)},
+		{Rel: "last", Href: pageUrl(lastPage)},
+	}
+	if page > 1 {
+		channel.AtomLink = append(channel.AtomLink, AtomLink{Rel: "previous", Href: pageUrl(page - 1)})
+	}
+	if page < lastPage {
+		channel.AtomLink = append(channel.AtomLink, AtomLink{Rel: "next", Href: pageUrl(page + 1)})
+	}
+
+	// The archive changes with refreshes, pages are rendered once per feed
+	_, updated := currentFeed()
+	serveFeed(w, r, &renderedArchiveFeed, pageUrl(page), rss, updated)
+}
+
+var renderedArchiveFeed RenderedFeed
@@ -0,0 +1,47 @@
+package okorss
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveNodesChanges(t *testing.T) {
+
+	db, err := openArchive(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	node := func(id string, title string) Node {
+		return Node{ID: id, Title: title, Published: "2025-05-01T10:00:00"}
+	}
+	tests := []struct {
+		name  string
+		nodes []Node
+		want  map[string]string
+	}{
+		{"empty archive", []Node{node("1", "Pierwszy"), node("2", "Drugi")}, nil},
+		{"unchanged", []Node{node("1", "Pierwszy"), node("2", "Drugi")}, map[string]string{}},
+		{"new article", []Node{node("3", "Trzeci"), node("1", "Pierwszy")}, map[string]string{"3": ChangeNew}},
+		{"edited title", []Node{node("1", "Pierwszy, poprawiony"), node("2", "Drugi")}, map[string]string{"1": ChangeUpdated}},
+		{"edited and new", []Node{node("2", "Drugi, poprawiony"), node("4", "Czwarty")}, map[string]string{"2": ChangeUpdated, "4": ChangeNew}},
+	}
+
+	for _, test := range tests {
+		// Timestamps have a resolution of a second, move earlier fetches
+		// back so they can be told apart from this one
+		_, err = db.Exec("UPDATE articles SET first_seen = '2025-01-01T00:00:00Z', last_seen = '2025-01-01T00:00:00Z', updated = CASE WHEN updated = '' THEN '' ELSE '2025-01-01T00:00:00Z' END")
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := archiveNodes(db, test.nodes)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if (changes == nil) != (test.want == nil) || !maps.Equal(changes, test.want) {
+			t.Errorf("%s: changes = %v, want %v", test.name, changes, test.want)
+		}
+	}
+}
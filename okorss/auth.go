@@ -0,0 +1,74 @@
+package okorss
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Accepted credentials for a route. Either a Basic auth user or a bearer
+// token lets the request through
+type AuthConfig struct {
+	Users  map[string]string `json:"users"`
+	Tokens []string          `json:"tokens"`
+}
+
+// Compare secrets in constant time, hashing first so length isn't leaked
+func secretEqual(given string, expected string) bool {
+	givenHash := sha256.Sum256([]byte(given))
+	expectedHash := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(givenHash[:], expectedHash[:]) == 1
+}
+
+func (auth AuthConfig) allows(r *http.Request) bool {
+
+	user, password, ok := r.BasicAuth()
+	if ok {
+		expected, known := auth.Users[user]
+		return known && secretEqual(password, expected)
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok {
+		for _, expected := range auth.Tokens {
+			if secretEqual(token, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Credentials configured for path, or the "*" entry applying to all
+// routes without their own
+func routeAuth(path string) AuthConfig {
+	auth, ok := config.Auth[path]
+	if !ok {
+		auth = config.Auth["*"]
+	}
+	return auth
+}
+
+func hasAuth(path string) bool {
+	auth := routeAuth(path)
+	return len(auth.Users) > 0 || len(auth.Tokens) > 0
+}
+
+// Protect route with credentials configured for its path
+func withAuth(path string, handler http.HandlerFunc) http.HandlerFunc {
+
+	if !hasAuth(path) {
+		return handler
+	}
+	auth := routeAuth(path)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.allows(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oko-rss", charset="UTF-8"`)
+			http.Error(w, "authorization required", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,147 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Point the configured API URL at a different page. The GraphQL query
+// takes its offset and limit from the JSON encoded "variables" parameter
+func pageUrl(apiUrl string, offset int, limit int) (string, error) {
+
+	parsed, err := url.Parse(apiUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	variables := make(map[string]interface{})
+	if query.Get("variables") != "" {
+		err = json.Unmarshal([]byte(query.Get("variables")), &variables)
+		if err != nil {
+			return "", fmt.Errorf("parsing variables: %w", err)
+		}
+	}
+
+	variables["offset"] = offset
+	if limit > 0 {
+		variables["limit"] = limit
+	}
+
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	query.Set("variables", string(encoded))
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+// Page size set in the configured API URL
+func pageLimit(apiUrl string) int {
+
+	parsed, err := url.Parse(apiUrl)
+	if err != nil {
+		return 0
+	}
+
+	var variables struct {
+		Limit int `json:"limit"`
+	}
+	json.Unmarshal([]byte(parsed.Query().Get("variables")), &variables)
+	return variables.Limit
+}
+
+// Walk the API pagination from the newest articles backwards and store
+// everything in the archive. Progress is saved after every page, so an
+// interrupted backfill continues where it stopped
+func backfillCommand(args []string) {
+
+	var configPath string
+	var limit, pages int
+	var delay time.Duration
+	var restart bool
+
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "NO_CONFIG", "config file path")
+	flags.StringVar(&configPath, "config", "NO_CONFIG", "config file path")
+	flags.IntVar(&limit, "limit", 0, "articles per page (default taken from API URL)")
+	flags.IntVar(&pages, "pages", 0, "stop after this many pages (default all)")
+	flags.DurationVar(&delay, "delay", 2*time.Second, "pause between pages")
+	flags.BoolVar(&restart, "restart", false, "ignore saved progress and start from the newest articles")
+	flags.Parse(args)
+
+	if configPath == "NO_CONFIG" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+	loadConfig(configPath)
+
+	if !openConfiguredArchive() {
+		return
+	}
+	defer archive.Close()
+
+	if limit == 0 {
+		limit = pageLimit(config.Url)
+	}
+	if limit == 0 {
+		limit = 10
+	}
+
+	// Resume from saved offset
+	offset := 0
+	if !restart {
+		saved, err := archiveMeta(archive, "backfill_offset")
+		if err != nil {
+			fatal("Error while reading backfill progress", "error", err)
+		}
+		if saved != "" {
+			offset, _ = strconv.Atoi(saved)
+			slog.Info("Resuming backfill", "offset", offset)
+		}
+	}
+
+	for page := 0; pages == 0 || page < pages; page++ {
+
+		// Be polite to the API
+		if page > 0 {
+			time.Sleep(delay)
+		}
+
+		url, err := pageUrl(config.Url, offset, limit)
+		if err != nil {
+			fatal("Error while building page URL", "error", err)
+		}
+
+		slog.Info("Fetching articles", "feed", config.Url, "offset", offset, "limit", limit)
+		nodes, err := fetchNodes(context.Background(), url)
+		if err != nil {
+			fatal("Error while fetching articles", "error", err)
+		}
+
+		// Reached the oldest article
+		if len(nodes) == 0 {
+			slog.Info("No more articles, backfill finished")
+			break
+		}
+
+		_, err = archiveNodes(archive, nodes)
+		if err != nil {
+			fatal("Error while archiving articles", "error", err)
+		}
+
+		offset += len(nodes)
+		err = setArchiveMeta(archive, "backfill_offset", strconv.Itoa(offset))
+		if err != nil {
+			fatal("Error while saving backfill progress", "error", err)
+		}
+	}
+}
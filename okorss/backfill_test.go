@@ -0,0 +1,46 @@
+package okorss
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPageUrl(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		apiUrl    string
+		offset    int
+		limit     int
+		variables string
+		wantErr   bool
+	}{
+		{"replaces offset", `https://api.example/?operationName=X&variables={"offset":0,"limit":10}`, 20, 0, `{"limit":10,"offset":20}`, false},
+		{"replaces limit", `https://api.example/?variables={"offset":0,"limit":10}`, 0, 50, `{"limit":50,"offset":0}`, false},
+		{"keeps other variables", `https://api.example/?variables={"category":"kraj"}`, 5, 0, `{"category":"kraj","offset":5}`, false},
+		{"without variables", `https://api.example/`, 5, 3, `{"limit":3,"offset":5}`, false},
+		{"broken variables", `https://api.example/?variables={`, 0, 0, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := pageUrl(test.apiUrl, test.offset, test.limit)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("pageUrl() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := url.Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variables := parsed.Query().Get("variables"); variables != test.variables {
+				t.Errorf("variables = %s, want %s", variables, test.variables)
+			}
+		})
+	}
+}
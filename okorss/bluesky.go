@@ -0,0 +1,190 @@
+package okorss
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bluesky limits record creation, stay well below it when many items appear
+const blueskyDefaultDelay = Duration(5 * time.Second)
+
+// Posts are limited to 300 graphemes, runes are a close enough estimate
+const blueskyMaxPostLength = 300
+
+type BlueskyConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Service     string   `json:"service"`
+	Handle      string   `json:"handle"`
+	AppPassword string   `json:"app_password"`
+	Delay       Duration `json:"delay"`
+}
+
+type BlueskyNotifier struct {
+	config BlueskyConfig
+
+	// Session tokens, refreshed when expired
+	mutex     sync.Mutex
+	accessJwt string
+	did       string
+}
+
+type blueskyBlob map[string]interface{}
+
+func newBlueskyNotifier(config BlueskyConfig) *BlueskyNotifier {
+
+	config.Service = strings.TrimSuffix(config.Service, "/")
+	if config.Service == "" {
+		config.Service = "https://bsky.social"
+	}
+	if config.Delay == 0 {
+		config.Delay = blueskyDefaultDelay
+	}
+
+	return &BlueskyNotifier{config: config}
+}
+
+func (bluesky *BlueskyNotifier) Name() string {
+	return "Bluesky " + bluesky.config.Handle
+}
+
+func (bluesky *BlueskyNotifier) xrpc(method string) string {
+	return bluesky.config.Service + "/xrpc/" + method
+}
+
+// Log in with app password
+func (bluesky *BlueskyNotifier) createSession() error {
+
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		Did       string `json:"did"`
+	}
+	credentials := map[string]string{
+		"identifier": bluesky.config.Handle,
+		"password":   bluesky.config.AppPassword,
+	}
+	err := sendJson("POST", bluesky.xrpc("com.atproto.server.createSession"), nil, credentials, &session)
+	if err != nil {
+		return err
+	}
+
+	bluesky.accessJwt = session.AccessJwt
+	bluesky.did = session.Did
+	return nil
+}
+
+func (bluesky *BlueskyNotifier) authorization() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + bluesky.accessJwt}
+}
+
+// Upload thumbnail for the link card
+func (bluesky *BlueskyNotifier) uploadThumbnail(url string) (blueskyBlob, error) {
+
+	image, contentType, err := downloadImage(url)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", bluesky.xrpc("com.atproto.repo.uploadBlob"), bytes.NewReader(image))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Authorization", "Bearer "+bluesky.accessJwt)
+
+	var uploaded struct {
+		Blob blueskyBlob `json:"blob"`
+	}
+	err = doRequest(request, &uploaded)
+	return uploaded.Blob, err
+}
+
+func (bluesky *BlueskyNotifier) post(item NotifyItem) error {
+
+	text := []rune(item.Title)
+	if len(text) > blueskyMaxPostLength {
+		text = append(text[:blueskyMaxPostLength-1], '…')
+	}
+
+	// Link card shown below the post
+	external := map[string]interface{}{
+		"uri":         item.Link,
+		"title":       item.Title,
+		"description": item.Lead,
+	}
+	if item.Image != "" {
+		thumbnail, err := bluesky.uploadThumbnail(item.Image)
+		if err != nil {
+			slog.Warn("Error while uploading thumbnail", "notifier", bluesky.Name(), "error", err)
+		} else {
+			external["thumb"] = thumbnail
+		}
+	}
+
+	record := map[string]interface{}{
+		"repo":       bluesky.did,
+		"collection": "app.bsky.feed.post",
+		"record": map[string]interface{}{
+			"$type":     "app.bsky.feed.post",
+			"text":      string(text),
+			"createdAt": time.Now().UTC().Format(time.RFC3339),
+			"langs":     []string{"pl"},
+			"embed": map[string]interface{}{
+				"$type":    "app.bsky.embed.external",
+				"external": external,
+			},
+		},
+	}
+
+	return sendJson("POST", bluesky.xrpc("com.atproto.repo.createRecord"), bluesky.authorization(), record, nil)
+}
+
+func (bluesky *BlueskyNotifier) Notify(nodes []Node) error {
+
+	bluesky.mutex.Lock()
+	defer bluesky.mutex.Unlock()
+
+	// Access tokens are short lived, start every batch with a fresh session
+	err := retry(3, 5*time.Second, bluesky.createSession)
+	if err != nil {
+		return err
+	}
+
+	for i, node := range nodes {
+		if i > 0 {
+			time.Sleep(time.Duration(bluesky.config.Delay))
+		}
+
+		err = retryUnsent(3, 10*time.Second, func() error {
+			return bluesky.post(newNotifyItem(node))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Like retry, but only for requests that never reached the server. Posts
+// have no idempotency key, one that timed out may have been created
+func retryUnsent(attempts int, wait time.Duration, call func() error) error {
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		err = call()
+		var opError *net.OpError
+		if err == nil || !errors.As(err, &opError) || opError.Op != "dial" {
+			return err
+		}
+	}
+	return err
+}
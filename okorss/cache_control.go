@@ -0,0 +1,60 @@
+package okorss
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Adds Cache-Control to successful responses only, errors shouldn't be
+// cached by CDNs for as long as the content
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (writer *cacheControlWriter) WriteHeader(status int) {
+	if !writer.wroteHeader && status < 400 {
+		writer.Header().Set("Cache-Control", writer.value)
+	}
+	writer.wroteHeader = true
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *cacheControlWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *cacheControlWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(writer.ResponseWriter).Hijack()
+}
+
+func (writer *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// Set Cache-Control configured for the path, or the "*" entry applying to
+// all routes without their own
+func withCacheControl(path string, handler http.HandlerFunc) http.HandlerFunc {
+
+	value := cacheControlFor(path)
+	if value == "" {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+	}
+}
+
+func cacheControlFor(path string) string {
+	value, ok := config.CacheControl[path]
+	if !ok {
+		value = config.CacheControl["*"]
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package okorss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var errInternalAddress = errors.New("callback address is not public")
+
+// Client for callbacks given by subscribers, like rssCloud and WebSub ones.
+// Addresses are checked as connections are made, so neither DNS answers
+// nor redirects can point it at loopback or internal networks
+var callbackClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        16,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+func dialPublicOnly(network string, address string, conn syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !publicAddress(addrPort.Addr()) {
+		return errInternalAddress
+	}
+	return nil
+}
+
+// Reachable on the internet, not loopback, private, link-local, multicast
+// or unspecified
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// Resolve the callback host and refuse it unless all its addresses are
+// public, so registrations fail up front rather than on every notification
+func checkCallbackHost(ctx context.Context, host string) error {
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !publicAddress(ip) {
+			return errInternalAddress
+		}
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !publicAddress(ip) {
+			return errInternalAddress
+		}
+	}
+	return nil
+}
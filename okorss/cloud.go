@@ -0,0 +1,268 @@
+package okorss
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Domain and port default to the ones feeds are requested at
+type CloudConfig struct {
+	Enabled bool   `json:"enabled"`
+	Domain  string `json:"domain"`
+	Port    int    `json:"port"`
+}
+
+// Tells readers where to ask for update notifications, see
+// https://www.rssboard.org/rsscloud-interface
+type RssCloud struct {
+	Domain            string `xml:"domain,attr"`
+	Port              int    `xml:"port,attr"`
+	Path              string `xml:"path,attr"`
+	RegisterProcedure string `xml:"registerProcedure,attr"`
+	Protocol          string `xml:"protocol,attr"`
+}
+
+const cloudPath = "/rsscloud/pleaseNotify"
+
+// Subscribers renew at least this often, as the protocol requires
+const cloudSubscriptionTtl = 25 * time.Hour
+
+const maxCloudSubscriptions = 1000
+
+type cloudSubscription struct {
+	feed     string
+	callback string
+	expires  time.Time
+}
+
+var cloudSubscriptions struct {
+	sync.Mutex
+	byKey map[string]cloudSubscription
+}
+
+// Element for a feed served at the request, nil when disabled
+func feedCloud(r *http.Request) *RssCloud {
+
+	if !config.Cloud.Enabled {
+		return nil
+	}
+	cloud := &RssCloud{
+		Domain:   config.Cloud.Domain,
+		Port:     config.Cloud.Port,
+		Path:     requestBasePath(r) + cloudPath,
+		Protocol: "http-post",
+	}
+	base, err := url.Parse(requestBaseUrl(r))
+	if err != nil {
+		return nil
+	}
+	if cloud.Domain == "" {
+		cloud.Domain = base.Hostname()
+	}
+	if cloud.Port == 0 {
+		cloud.Port, _ = strconv.Atoi(base.Port())
+	}
+	if cloud.Port == 0 && base.Scheme == "https" {
+		cloud.Port = 443
+	} else if cloud.Port == 0 {
+		cloud.Port = 80
+	}
+	return cloud
+}
+
+// Registration request of the rssCloud protocol, form encoded
+func serveCloudNotify(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := registerCloud(r)
+	if err != nil {
+		slog.Info("Rejected rssCloud subscription", "client", clientIP(r), "error", err)
+		writeCloudResult(w, false, "Registration failed")
+		return
+	}
+	writeCloudResult(w, true, "Registered, renew within 25 hours to keep getting notifications")
+}
+
+func writeCloudResult(w http.ResponseWriter, success bool, message string) {
+	result := struct {
+		XMLName xml.Name `xml:"notifyResult"`
+		Success bool     `xml:"success,attr"`
+		Message string   `xml:"msg,attr"`
+	}{Success: success, Message: message}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+func registerCloud(r *http.Request) error {
+
+	err := r.ParseForm()
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	switch r.PostForm.Get("protocol") {
+	case "http-post":
+	case "https-post":
+		scheme = "https"
+	default:
+		return fmt.Errorf("unsupported protocol %q, only http-post is", r.PostForm.Get("protocol"))
+	}
+	port, err := strconv.Atoi(r.PostForm.Get("port"))
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", r.PostForm.Get("port"))
+	}
+	path := r.PostForm.Get("path")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var feeds []string
+	for i := 1; r.PostForm.Has("url" + strconv.Itoa(i)); i++ {
+		feed := r.PostForm.Get("url" + strconv.Itoa(i))
+		parsed, err := url.Parse(feed)
+		if err != nil || !isFeedRoute(strings.TrimPrefix(parsed.Path, requestBasePath(r))) {
+			return fmt.Errorf("%s is not a feed served here", feed)
+		}
+		feeds = append(feeds, feed)
+	}
+	if len(feeds) == 0 {
+		return fmt.Errorf("no feed to notify about given in url1")
+	}
+
+	// Callbacks with a domain answer a challenge, otherwise the client's
+	// address gets a test notification
+	domain := r.PostForm.Get("domain")
+	host := domain
+	if host == "" {
+		host = clientIP(r)
+	}
+	err = checkCallbackHost(r.Context(), host)
+	if err != nil {
+		return err
+	}
+	callback := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + path
+	for _, feed := range feeds {
+		if domain != "" {
+			err = verifyCloudChallenge(callback, feed)
+		} else {
+			err = sendCloudNotification(callback, feed)
+		}
+		if err != nil {
+			return fmt.Errorf("verifying %s: %w", callback, err)
+		}
+	}
+
+	cloudSubscriptions.Lock()
+	defer cloudSubscriptions.Unlock()
+	if cloudSubscriptions.byKey == nil {
+		cloudSubscriptions.byKey = make(map[string]cloudSubscription)
+	}
+	pruneCloudSubscriptions(time.Now())
+	for _, feed := range feeds {
+		key := feed + " " + callback
+		_, renewal := cloudSubscriptions.byKey[key]
+		if !renewal && len(cloudSubscriptions.byKey) >= maxCloudSubscriptions {
+			return fmt.Errorf("too many subscriptions")
+		}
+		cloudSubscriptions.byKey[key] = cloudSubscription{feed: feed, callback: callback, expires: time.Now().Add(cloudSubscriptionTtl)}
+		slog.Info("rssCloud subscription", "feed", feed, "callback", callback)
+	}
+	return nil
+}
+
+func isFeedRoute(path string) bool {
+	for _, route := range feedRoutes {
+		if route.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyCloudChallenge(callback string, feed string) error {
+
+	challenge := make([]byte, 16)
+	rand.Read(challenge)
+	expected := hex.EncodeToString(challenge)
+
+	response, err := callbackClient.Get(callback + "?" + url.Values{"url": {feed}, "challenge": {expected}}.Encode())
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("bad HTTP status: %s", response.Status)
+	}
+	if strings.TrimSpace(string(body)) != expected {
+		return fmt.Errorf("challenge not echoed back")
+	}
+	return nil
+}
+
+func sendCloudNotification(callback string, feed string) error {
+	request, err := http.NewRequest("POST", callback, strings.NewReader(url.Values{"url": {feed}}.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doRequestWith(callbackClient, request, nil)
+}
+
+// Caller holds the lock
+func pruneCloudSubscriptions(now time.Time) {
+	for key, subscription := range cloudSubscriptions.byKey {
+		if now.After(subscription.expires) {
+			delete(cloudSubscriptions.byKey, key)
+		}
+	}
+}
+
+// Notifies rssCloud subscribers when new articles appear
+type CloudNotifier struct{}
+
+func (CloudNotifier) Name() string {
+	return "rssCloud"
+}
+
+func (CloudNotifier) Notify(nodes []Node) error {
+
+	cloudSubscriptions.Lock()
+	pruneCloudSubscriptions(time.Now())
+	var subscriptions []cloudSubscription
+	for _, subscription := range cloudSubscriptions.byKey {
+		subscriptions = append(subscriptions, subscription)
+	}
+	cloudSubscriptions.Unlock()
+
+	// An unreachable subscriber doesn't stop the others being notified
+	for _, subscription := range subscriptions {
+		err := sendCloudNotification(subscription.callback, subscription.feed)
+		if err != nil {
+			slog.Warn("Error while notifying rssCloud subscriber", "callback", subscription.callback, "error", err)
+		}
+	}
+	return nil
+}
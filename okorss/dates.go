@@ -0,0 +1,72 @@
+package okorss
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// When the article was first seen, the given time for articles never seen
+// before. Stable across refreshes, unlike the fetch time
+func firstSeen(node Node, fallback time.Time) time.Time {
+	seenItems.Lock()
+	defer seenItems.Unlock()
+	item, ok := seenItems.items[nodeGuid(node)]
+	if !ok || item.FirstSeen.IsZero() {
+		return fallback
+	}
+	return item.FirstSeen
+}
+
+// Time in the field of the upstream article named by publish_time_fallback,
+// zero when there's none
+func fallbackPublishTime(node Node) time.Time {
+
+	if config.PublishTimeFallback == "" || len(node.Raw) == 0 {
+		return time.Time{}
+	}
+	value := gjson.GetBytes(node.Raw, config.PublishTimeFallback).String()
+	for _, layout := range []string{"2006-01-02T15:04:05", time.RFC3339} {
+		parsed, err := time.ParseInLocation(layout, value, time.UTC)
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// Articles whose publish time can't be parsed would end up dated year 1.
+// Ones without any are dated by publish_time_fallback, e.g. updated_at, or
+// when first seen. Malformed times are skipped with strict_dates, otherwise
+// dated when first seen too
+func checkPublishTimes(nodes []Node, fetched time.Time) []Node {
+
+	checked := nodes[:0:0]
+	for _, node := range nodes {
+		if node.Published == "" {
+			fallback := fallbackPublishTime(node)
+			if fallback.IsZero() {
+				fallback = firstSeen(node, fetched)
+			}
+			slog.Debug("Article without publish time", "id", node.ID, "dated", fallback)
+			node.Published = apiTime(fallback)
+			checked = append(checked, node)
+			continue
+		}
+		_, err := time.ParseInLocation("2006-01-02T15:04:05", node.Published, time.UTC)
+		if err == nil {
+			checked = append(checked, node)
+			continue
+		}
+		if config.StrictDates {
+			slog.Warn("Skipping article with unparseable publish time", "id", node.ID, "published", node.Published, "error", err)
+			continue
+		}
+		fallback := firstSeen(node, fetched)
+		slog.Warn("Unparseable publish time, dating article when first seen", "id", node.ID, "published", node.Published, "first_seen", fallback, "error", err)
+		node.Published = apiTime(fallback)
+		checked = append(checked, node)
+	}
+	return checked
+}
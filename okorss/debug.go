@@ -0,0 +1,46 @@
+package okorss
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+)
+
+// Set with --debug
+var debugEnabled bool
+
+const pprofPath = "/debug/pprof/"
+
+// Profiling endpoints of net/http/pprof
+func pprofHandler() *http.ServeMux {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pprofPath, pprof.Index)
+	handler.HandleFunc(pprofPath+"cmdline", pprof.Cmdline)
+	handler.HandleFunc(pprofPath+"profile", pprof.Profile)
+	handler.HandleFunc(pprofPath+"symbol", pprof.Symbol)
+	handler.HandleFunc(pprofPath+"trace", pprof.Trace)
+	return handler
+}
+
+// Serve profiling on a separate admin address, e.g. "127.0.0.1:6060",
+// which is meant to be kept private rather than protected by auth
+func serveDebug(ctx context.Context, wg *sync.WaitGroup, listener net.Listener) {
+
+	defer wg.Done()
+
+	slog.Info("Starting debug server", "address", config.DebugListen)
+	server := &http.Server{Handler: pprofHandler()}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	err := server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Error while serving debug endpoints", "error", err)
+	}
+}
@@ -0,0 +1,142 @@
+package okorss
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Kinds of change reported by the diff endpoints
+const (
+	ChangeNew     = "new"
+	ChangeUpdated = "updated"
+)
+
+type ItemChange struct {
+	Node   Node
+	Change string
+}
+
+// Changes found by the most recent refresh
+var lastDiff struct {
+	sync.Mutex
+	Generated time.Time
+	Changes   []ItemChange
+}
+
+// Work out which articles are new or changed since the previous fetch.
+// The archive's verdict is used when available, otherwise the seen items
+// state. Without either, the first fetch after start only sets the baseline.
+// A new archive gives no verdict, so it sets the baseline the same way
+func recordDiff(nodes []Node, archived map[string]string) []ItemChange {
+
+	var changes []ItemChange
+	for _, node := range nodes {
+		seenChange := markSeen(node)
+
+		change := seenChange
+		if archived != nil {
+			change = archived[node.ID]
+		}
+		if change != "" {
+			changes = append(changes, ItemChange{Node: node, Change: change})
+		}
+		slog.Debug("Compared item with previous fetches", "id", node.ID, "change", change)
+	}
+	seenItems.Lock()
+	seenItems.baseline = false
+	seenItems.Unlock()
+	saveSeenState()
+
+	lastDiff.Lock()
+	lastDiff.Generated = time.Now()
+	lastDiff.Changes = changes
+	lastDiff.Unlock()
+
+	return changes
+}
+
+func countNew(changes []ItemChange) int {
+	count := 0
+	for _, change := range changes {
+		if change.Change == ChangeNew {
+			count++
+		}
+	}
+	return count
+}
+
+func currentDiff() (time.Time, []ItemChange) {
+	lastDiff.Lock()
+	defer lastDiff.Unlock()
+	return lastDiff.Generated, lastDiff.Changes
+}
+
+type DiffItem struct {
+	ID        string `json:"id"`
+	Change    string `json:"change"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Published string `json:"published"`
+	Image     string `json:"image"`
+}
+
+func serveDiffJson(w http.ResponseWriter, r *http.Request) {
+
+	refreshIfStale()
+	if feedNotReady(w) {
+		return
+	}
+	generated, changes := currentDiff()
+
+	response := struct {
+		Generated time.Time  `json:"generated"`
+		Items     []DiffItem `json:"items"`
+	}{
+		Generated: generated,
+		Items:     []DiffItem{},
+	}
+	for _, change := range changes {
+		item, ok := itemPipeline.item(change.Node)
+		if !ok {
+			continue
+		}
+		response.Items = append(response.Items, DiffItem{
+			ID:        change.Node.ID,
+			Change:    change.Change,
+			Title:     item.Title,
+			Link:      item.Link,
+			Published: item.PubDate,
+			Image:     item.Enclosure.Url,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(response)
+}
+
+func serveDiffRss(w http.ResponseWriter, r *http.Request) {
+
+	refreshIfStale()
+	if feedNotReady(w) {
+		return
+	}
+	generated, changes := currentDiff()
+
+	var nodes []Node
+	for _, change := range changes {
+		nodes = append(nodes, change.Node)
+	}
+	rss := buildRss(nodes, itemPipeline)
+	rss.Channel.Title += " (nowe i zmienione)"
+	self := requestBaseUrl(r) + r.URL.Path
+	rss.Channel.AtomLink = []AtomLink{{Rel: "self", Href: self}}
+	rss.Channel.Cloud = feedCloud(r)
+	serveFeed(w, r, &renderedDiffFeed, self, rss, generated)
+}
+
+var renderedDiffFeed RenderedFeed
@@ -0,0 +1,285 @@
+package okorss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const digestDefaultTemplate = `<!DOCTYPE html>
+<html lang="pl">
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif; max-width: 640px; margin: auto">
+<h1>{{.Subject}}</h1>
+{{range .Items}}
+<div style="margin-bottom: 24px">
+{{if .Image}}<a href="{{.Link}}"><img src="{{.Image}}" alt="" style="max-width: 100%"></a>{{end}}
+<h2 style="margin-bottom: 4px"><a href="{{.Link}}">{{.Title}}</a></h2>
+<small>{{.Published.Format "02.01.2006 15:04"}}</small>
+{{if .Lead}}<p>{{.Lead}}</p>{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`
+
+type EmailDigestConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Schedule string `json:"schedule"`
+	Hour     int    `json:"hour"`
+	Weekday  string `json:"weekday"`
+	Timezone string `json:"timezone"`
+	SmtpConfig
+	Subject   string `json:"subject"`
+	Template  string `json:"template"`
+	StateFile string `json:"state_file"`
+}
+
+// Items waiting for the next digest and the ones already sent, so every
+// article is included exactly once even across restarts
+type DigestState struct {
+	Pending  []NotifyItem         `json:"pending"`
+	Included map[string]time.Time `json:"included"`
+	LastSent time.Time            `json:"last_sent"`
+}
+
+type EmailDigest struct {
+	config   EmailDigestConfig
+	template *template.Template
+	location *time.Location
+	weekday  time.Weekday
+
+	mutex sync.Mutex
+	state DigestState
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func newEmailDigest(config EmailDigestConfig) (*EmailDigest, error) {
+
+	digest := &EmailDigest{config: config, location: time.Local}
+
+	if config.Schedule == "" {
+		digest.config.Schedule = "daily"
+	}
+	if digest.config.Schedule != "daily" && digest.config.Schedule != "weekly" {
+		return nil, fmt.Errorf("unknown schedule %q, use daily or weekly", config.Schedule)
+	}
+	if config.Weekday != "" {
+		weekday, ok := weekdays[strings.ToLower(config.Weekday)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", config.Weekday)
+		}
+		digest.weekday = weekday
+	} else {
+		digest.weekday = time.Monday
+	}
+	if config.Timezone != "" {
+		location, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		digest.location = location
+	}
+	if config.Subject == "" {
+		digest.config.Subject = "OKO.press – przegląd artykułów"
+	}
+
+	// Template from config replaces the built in one
+	source := digestDefaultTemplate
+	if config.Template != "" {
+		data, err := os.ReadFile(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		source = string(data)
+	}
+	var err error
+	digest.template, err = template.New("digest").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	err = digest.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	notifyWorkers.Add(1)
+	go digest.scheduler()
+	return digest, nil
+}
+
+func (digest *EmailDigest) Name() string {
+	return "email digest"
+}
+
+func (digest *EmailDigest) loadState() error {
+
+	digest.state.Included = make(map[string]time.Time)
+	if digest.config.StateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(digest.config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, &digest.state)
+	if digest.state.Included == nil {
+		digest.state.Included = make(map[string]time.Time)
+	}
+	return err
+}
+
+func (digest *EmailDigest) saveState() {
+
+	if digest.config.StateFile == "" {
+		return
+	}
+
+	// Forget sent items once they can't come back from the API
+	for guid, sent := range digest.state.Included {
+		if time.Since(sent) > seenStateRetention {
+			delete(digest.state.Included, guid)
+		}
+	}
+
+	data, err := json.Marshal(digest.state)
+	if err == nil {
+		err = os.WriteFile(digest.config.StateFile, data, 0600)
+	}
+	if err != nil {
+		slog.Error("Error while saving digest state", "error", err)
+	}
+}
+
+// Queue items for the next digest
+func (digest *EmailDigest) Notify(nodes []Node) error {
+
+	digest.mutex.Lock()
+	defer digest.mutex.Unlock()
+
+	queued := make(map[string]bool)
+	for _, item := range digest.state.Pending {
+		queued[item.ID] = true
+	}
+	for _, node := range nodes {
+		_, included := digest.state.Included[node.ID]
+		if included || queued[node.ID] {
+			continue
+		}
+		digest.state.Pending = append(digest.state.Pending, newNotifyItem(node))
+		queued[node.ID] = true
+	}
+
+	digest.saveState()
+	return nil
+}
+
+// Next moment a digest is due after given time
+func (digest *EmailDigest) nextSend(after time.Time) time.Time {
+
+	local := after.In(digest.location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), digest.config.Hour, 0, 0, 0, digest.location)
+	for !next.After(local) || (digest.config.Schedule == "weekly" && next.Weekday() != digest.weekday) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (digest *EmailDigest) scheduler() {
+
+	defer notifyWorkers.Done()
+	defer reportPanic()
+
+	for true {
+		// Catch up on a digest missed while the process was down
+		digest.mutex.Lock()
+		next := digest.nextSend(time.Now())
+		if !digest.state.LastSent.IsZero() {
+			missed := digest.nextSend(digest.state.LastSent)
+			if missed.Before(next) {
+				next = missed
+			}
+		}
+		digest.mutex.Unlock()
+
+		if !sleepUntilStopped(time.Until(next)) {
+			return
+		}
+
+		err := digest.send()
+		if err != nil {
+			slog.Error("Error while sending digest", "notifier", digest.Name(), "error", err)
+			if !sleepUntilStopped(15 * time.Minute) {
+				return
+			}
+		}
+	}
+}
+
+// Sleep unless notifiers are stopped meanwhile, false when they were
+func sleepUntilStopped(duration time.Duration) bool {
+	select {
+	case <-notifyStop:
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
+
+// Send pending items, if any, and mark them as included
+func (digest *EmailDigest) send() error {
+
+	digest.mutex.Lock()
+	defer digest.mutex.Unlock()
+
+	if len(digest.state.Pending) == 0 {
+		digest.state.LastSent = time.Now()
+		digest.saveState()
+		return nil
+	}
+
+	var body bytes.Buffer
+	err := digest.template.Execute(&body, map[string]interface{}{
+		"Subject": digest.config.Subject,
+		"Items":   digest.state.Pending,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = sendMail(digest.config.SmtpConfig, mailMessage(digest.config.SmtpConfig, digest.config.Subject, "text/html", body.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, item := range digest.state.Pending {
+		digest.state.Included[item.ID] = now
+	}
+	slog.Info("Sent email digest", "articles", len(digest.state.Pending))
+	digest.state.Pending = nil
+	digest.state.LastSent = now
+	digest.saveState()
+	return nil
+}
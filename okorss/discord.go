@@ -0,0 +1,103 @@
+package okorss
+
+import (
+	"time"
+)
+
+// Discord accepts at most 10 embeds per message, titles of 256 characters
+// and 6000 characters in all embeds of a message. Leads are shortened so
+// several items still fit in one message
+const (
+	discordMaxEmbeds      = 10
+	discordMaxTitle       = 256
+	discordMaxDescription = 500
+	discordMaxMessage     = 6000
+	discordColor          = 0xE30613
+)
+
+type DiscordConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Webhooks []string `json:"webhooks"`
+	Username string   `json:"username"`
+}
+
+type DiscordNotifier struct {
+	config DiscordConfig
+}
+
+func (discord DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// Shorten to at most max characters, marking the cut with an ellipsis
+func discordTruncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(append(runes[:max-1], '…'))
+}
+
+// Embed for the item and the characters it counts towards the message limit
+func discordEmbed(item NotifyItem) (map[string]interface{}, int) {
+
+	title := discordTruncate(item.Title, discordMaxTitle)
+	description := discordTruncate(item.Lead, discordMaxDescription)
+
+	embed := map[string]interface{}{
+		"title":       title,
+		"url":         item.Link,
+		"description": description,
+		"color":       discordColor,
+	}
+	if !item.Published.IsZero() {
+		embed["timestamp"] = item.Published.Format(time.RFC3339)
+	}
+	if item.Image != "" {
+		embed["thumbnail"] = map[string]string{"url": item.Image}
+	}
+	return embed, len([]rune(title)) + len([]rune(description))
+}
+
+func (discord DiscordNotifier) Notify(nodes []Node) error {
+
+	// Split items into batches of embeds, by count and total size
+	var batches [][]interface{}
+	size := 0
+	for _, node := range nodes {
+		embed, embedSize := discordEmbed(newNotifyItem(node))
+		last := len(batches) - 1
+		if last < 0 || len(batches[last]) == discordMaxEmbeds || size+embedSize > discordMaxMessage {
+			batches = append(batches, nil)
+			last++
+			size = 0
+		}
+		batches[last] = append(batches[last], embed)
+		size += embedSize
+	}
+
+	var lastErr error
+	for _, webhook := range discord.config.Webhooks {
+		for i, embeds := range batches {
+
+			// Webhooks allow only a few requests per second
+			if i > 0 {
+				time.Sleep(time.Second)
+			}
+
+			message := map[string]interface{}{"embeds": embeds}
+			if discord.config.Username != "" {
+				message["username"] = discord.config.Username
+			}
+			err := retry(3, 5*time.Second, func() error {
+				return sendJson("POST", webhook, nil, message, nil)
+			})
+			if err != nil {
+				lastErr = err
+				break
+			}
+		}
+	}
+
+	return lastErr
+}
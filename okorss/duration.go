@@ -0,0 +1,38 @@
+package okorss
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration in config, given as a string like "15m" or "1h30m". Plain
+// numbers are read as seconds, as in older configs
+type Duration time.Duration
+
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case float64:
+		*duration = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*duration = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+func (duration Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(duration).String())
+}
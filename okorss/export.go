@@ -0,0 +1,193 @@
+package okorss
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Flat representation of an archived article used by exports
+type ExportedArticle struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Link       string          `json:"link"`
+	Published  string          `json:"published"`
+	Updated    string          `json:"updated,omitempty"`
+	Image      string          `json:"image"`
+	Categories []string        `json:"categories"`
+	FirstSeen  string          `json:"first_seen"`
+	LastSeen   string          `json:"last_seen"`
+	Raw        json.RawMessage `json:"raw"`
+}
+
+var exportCsvHeader = []string{"id", "title", "link", "published", "updated", "image", "categories", "first_seen", "last_seen"}
+
+func exportArticle(article ArchivedArticle) ExportedArticle {
+
+	exported := ExportedArticle{
+		ID:         article.ID,
+		Title:      article.Title,
+		Link:       nodeLink(article.Node),
+		Published:  article.Published,
+		Image:      article.Image.Url,
+		Categories: []string{},
+		FirstSeen:  article.FirstSeen.Format(time.RFC3339),
+		LastSeen:   article.LastSeen.Format(time.RFC3339),
+		Raw:        article.Raw,
+	}
+	if !article.Updated.IsZero() {
+		exported.Updated = article.Updated.Format(time.RFC3339)
+	}
+	for _, category := range article.Categories {
+		exported.Categories = append(exported.Categories, category.Name)
+	}
+
+	return exported
+}
+
+// Check if article belongs to category, matched by name or slug
+func inCategory(node Node, category string) bool {
+	for _, candidate := range node.Categories {
+		if strings.EqualFold(candidate.Name, category) || strings.EqualFold(candidate.Slug, category) {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyCategory(node Node, categories []string) bool {
+	for _, category := range categories {
+		if inCategory(node, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert a YYYY-MM-DD command line date into the API time format
+func exportBound(date string) (string, error) {
+	if date == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format("2006-01-02T15:04:05"), nil
+}
+
+func writeJsonl(output io.Writer, articles []ExportedArticle) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	for _, article := range articles {
+		err := encoder.Encode(article)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCsv(output io.Writer, articles []ExportedArticle) error {
+	writer := csv.NewWriter(output)
+	writer.Write(exportCsvHeader)
+	for _, article := range articles {
+		writer.Write([]string{
+			article.ID,
+			article.Title,
+			article.Link,
+			article.Published,
+			article.Updated,
+			article.Image,
+			strings.Join(article.Categories, ";"),
+			article.FirstSeen,
+			article.LastSeen,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Dump archived articles for analysis or migration
+func exportCommand(args []string) {
+
+	var configPath, format, since, until, category, outputPath string
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "NO_CONFIG", "config file path")
+	flags.StringVar(&configPath, "config", "NO_CONFIG", "config file path")
+	flags.StringVar(&format, "format", "jsonl", "output format: jsonl or csv")
+	flags.StringVar(&since, "since", "", "only articles published on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&until, "until", "", "only articles published before this date (YYYY-MM-DD)")
+	flags.StringVar(&category, "category", "", "only articles in this category (name or slug)")
+	flags.StringVar(&outputPath, "o", "-", "output file, - for standard output")
+	flags.Parse(args)
+
+	if configPath == "NO_CONFIG" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+	if format != "jsonl" && format != "csv" {
+		fmt.Printf("Unknown format %q, use jsonl or csv!", format)
+		return
+	}
+	sinceBound, err := exportBound(since)
+	if err != nil {
+		fatal("Error while parsing --since date", "error", err)
+	}
+	untilBound, err := exportBound(until)
+	if err != nil {
+		fatal("Error while parsing --until date", "error", err)
+	}
+
+	loadConfig(configPath)
+	if !openConfiguredArchive() {
+		return
+	}
+	defer archive.Close()
+
+	articles, err := archivedArticles(archive, sinceBound, untilBound)
+	if err != nil {
+		fatal("Error while reading archive", "error", err)
+	}
+
+	var exported []ExportedArticle
+	for _, article := range articles {
+		if category != "" && !inCategory(article.Node, category) {
+			continue
+		}
+		exported = append(exported, exportArticle(article))
+	}
+
+	// Write to file or standard output
+	var output io.Writer = os.Stdout
+	if outputPath != "-" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			fatal("Error while creating output file", "error", err)
+		}
+		defer file.Close()
+		output = file
+	}
+	buffered := bufio.NewWriter(output)
+
+	if format == "csv" {
+		err = writeCsv(buffered, exported)
+	} else {
+		err = writeJsonl(buffered, exported)
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		fatal("Error while writing export", "error", err)
+	}
+	slog.Info("Exported articles", "articles", len(exported))
+}
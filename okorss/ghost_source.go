@@ -0,0 +1,118 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Post as returned by the Ghost Content API with authors and tags included
+type GhostPost struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Slug          string `json:"slug"`
+	Url           string `json:"url"`
+	Excerpt       string `json:"excerpt"`
+	CustomExcerpt string `json:"custom_excerpt"`
+	FeatureImage  string `json:"feature_image"`
+	PublishedAt   string `json:"published_at"`
+	Authors       []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Tags []struct {
+		Name       string `json:"name"`
+		Slug       string `json:"slug"`
+		Visibility string `json:"visibility"`
+	} `json:"tags"`
+}
+
+// Posts of a Ghost site, source url being the site address and key its
+// Content API key, see Ghost Admin → Integrations
+type GhostSource struct {
+	config SourceConfig
+}
+
+func newGhostSource(config Config) (Source, error) {
+	source := config.Source
+	if source.Url == "" || source.Key == "" {
+		return nil, fmt.Errorf("source url and key are required for the ghost source")
+	}
+	if source.Limit == 0 {
+		source.Limit = 20
+	}
+	return GhostSource{config: source}, nil
+}
+
+func (source GhostSource) Name() string {
+	return "Ghost " + source.config.Url
+}
+
+func (source GhostSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	query := url.Values{}
+	query.Set("key", source.config.Key)
+	query.Set("include", "authors,tags")
+	query.Set("limit", strconv.Itoa(source.config.Limit))
+	endpoint := strings.TrimSuffix(source.config.Url, "/") + "/ghost/api/content/posts/?" + query.Encode()
+
+	headers := map[string]string{"Accept-Version": "v5.0"}
+	for name, value := range source.config.Headers {
+		headers[name] = value
+	}
+
+	body, err := fetchBody(ctx, endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Posts []GhostPost `json:"posts"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("parsing posts: %w", err)}
+	}
+
+	var nodes []Node
+	for _, post := range response.Posts {
+		nodes = append(nodes, post.node())
+	}
+	return nodes, nil
+}
+
+func (post GhostPost) node() Node {
+
+	var node Node
+	node.ID = post.ID
+	node.Title = post.Title
+	node.Link = post.Url
+	node.Image.Url = post.FeatureImage
+	node.SeoFields.Slug = post.Slug
+
+	// Excerpt is generated from the content when no custom one is set
+	node.Lead = post.CustomExcerpt
+	if node.Lead == "" {
+		node.Lead = post.Excerpt
+	}
+
+	published, err := time.Parse(time.RFC3339, post.PublishedAt)
+	if err == nil {
+		node.Published = apiTime(published)
+	}
+
+	for _, author := range post.Authors {
+		node.Authors = append(node.Authors, Author{Name: author.Name})
+	}
+	// Internal tags, starting with #, aren't meant for readers
+	for _, tag := range post.Tags {
+		if tag.Visibility != "internal" {
+			node.Categories = append(node.Categories, Category{Name: tag.Name, Slug: tag.Slug})
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,106 @@
+package okorss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Set once a handler has been created. Config, feed and seen items are
+// package state, so there's one handler per process, even after Close
+var handlerCreated atomic.Bool
+
+// Routes of an embedded instance, refreshing the feed in the background
+// until closed
+type Handler struct {
+	http.Handler
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed sync.Once
+}
+
+// Feed routes, health checks and metrics as a handler, for mounting in
+// another Go service under its own mux and TLS setup:
+//
+//	cfg.BasePath = "/oko"
+//	handler, err := okorss.NewHandler(cfg)
+//	defer handler.Close()
+//	mux.Handle("/oko/", http.StripPrefix("/oko", handler))
+//
+// Links in feeds and pages are built with the base path, or the
+// X-Forwarded-Prefix of a trusted proxy. Logging goes through the default
+// slog logger of the service. NewHandler can be called only once per
+// process, later calls return an error, also after Close
+func NewHandler(cfg Config) (*Handler, error) {
+
+	if !handlerCreated.CompareAndSwap(false, true) {
+		return nil, errors.New("only one handler can be created per process")
+	}
+	config = cfg
+
+	if config.Archive != "" {
+		var err error
+		archive, err = openArchive(config.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("opening archive: %w", err)
+		}
+	}
+	if config.StateFile != "" {
+		err := loadSeenState(config.StateFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading state file: %w", err)
+		}
+	}
+	err := setupFeed()
+	if err != nil {
+		return nil, err
+	}
+	err = registerRoutes()
+	if err != nil {
+		return nil, err
+	}
+	err = setupTrustedProxies()
+	if err != nil {
+		return nil, fmt.Errorf("parsing trusted proxies: %w", err)
+	}
+	startNotifiers()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := &Handler{
+		Handler: accessLog(rateLimit(config.RateLimit, sentryHandler(mux))),
+		cancel:  cancel,
+	}
+	handler.wg.Add(1)
+	go cron(ctx, &handler.wg)
+	if archive != nil && (config.KeepDays > 0 || config.KeepItems > 0) {
+		handler.wg.Add(1)
+		go pruner(ctx, &handler.wg)
+	}
+	return handler, nil
+}
+
+// Stop refreshing and every background task, letting a refresh or
+// notification in progress finish, disconnect WebSocket and long poll
+// clients and close the archive. Meant for when the service no longer
+// routes requests to it
+func (handler *Handler) Close() error {
+
+	var err error
+	handler.closed.Do(func() {
+		handler.cancel()
+		handler.wg.Wait()
+		lazyRefreshes.Wait()
+		closeWebSockets()
+		stopPolls()
+		stopNotifiers()
+		webSubDistributing.Lock()
+		webSubVerifications.Wait()
+		if archive != nil {
+			err = archive.Close()
+		}
+	})
+	return err
+}
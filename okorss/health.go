@@ -0,0 +1,113 @@
+package okorss
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Feed older than ready_max_age in config, or missing three scheduled
+// refreshes in a row, so a single slow or failed refresh isn't fatal
+func feedStale(updated time.Time, now time.Time) bool {
+	if config.ReadyMaxAge > 0 {
+		return now.Sub(updated) > time.Duration(config.ReadyMaxAge)
+	}
+	if lazyOnly() {
+		return false
+	}
+	return now.After(nextRefresh(nextRefresh(nextRefresh(updated))))
+}
+
+// Process is up and serving
+func serveHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// A feed was generated and is fresh enough to be served
+func serveReadyz(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, updated := currentFeed()
+	if updated.IsZero() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "feed not generated yet")
+		return
+	}
+
+	age := time.Since(updated)
+	if feedStale(updated, time.Now()) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "feed is stale, last updated %s ago\n", age.Round(time.Second))
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+// Check readiness of the local instance, exiting 0 when ready and 1 when
+// not, for Docker's HEALTHCHECK in images without curl or wget:
+//
+//	HEALTHCHECK CMD ["oko-rss", "healthcheck", "-c", "/config.json"]
+func healthcheckCommand(args []string) {
+
+	var configPath, address string
+	var timeout time.Duration
+
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "", "config file path, for listen and TLS settings")
+	flags.StringVar(&configPath, "config", "", "config file path, for listen and TLS settings")
+	flags.StringVar(&port, "p", "8000", "port number")
+	flags.StringVar(&port, "port", "8000", "port number")
+	flags.StringVar(&address, "url", "", "readiness URL, instead of one derived from config")
+	flags.DurationVar(&timeout, "timeout", 5*time.Second, "request timeout")
+	flags.Parse(args)
+
+	if configPath != "" {
+		loadConfig(configPath)
+	}
+
+	// The certificate is for the public name, not localhost
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	if address == "" {
+		address = "http://127.0.0.1:" + port + "/readyz"
+		if config.TlsCert != "" || len(config.Acme.Domains) > 0 {
+			address = "https://127.0.0.1:" + port + "/readyz"
+		}
+		if strings.HasPrefix(config.Listen, "unix:") {
+			path := strings.TrimPrefix(config.Listen, "unix:")
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}
+		} else if config.Listen != "" {
+			host, listenPort, err := net.SplitHostPort(config.Listen)
+			if err == nil {
+				if host == "" || host == "0.0.0.0" || host == "::" {
+					host = "127.0.0.1"
+				}
+				address = strings.Replace(address, "127.0.0.1:"+port, net.JoinHostPort(host, listenPort), 1)
+			}
+		}
+	}
+
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	response, err := client.Get(address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unhealthy:", err)
+		os.Exit(1)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		fmt.Fprintln(os.Stderr, "unhealthy:", response.Status)
+		os.Exit(1)
+	}
+	fmt.Println("healthy")
+}
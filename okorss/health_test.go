@@ -0,0 +1,39 @@
+package okorss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedStale(t *testing.T) {
+
+	defer func(saved Config) { config = saved }(config)
+
+	updated := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		maxAge      time.Duration
+		readyMaxAge time.Duration
+		age         time.Duration
+		want        bool
+	}{
+		{"fresh", 5 * time.Minute, 0, 0, time.Minute, false},
+		{"one missed refresh", 5 * time.Minute, 0, 0, 7 * time.Minute, false},
+		{"three missed refreshes", 5 * time.Minute, 0, 0, 16 * time.Minute, true},
+		{"within ready_max_age", 5 * time.Minute, 0, time.Hour, 30 * time.Minute, false},
+		{"past ready_max_age", 5 * time.Minute, 0, time.Hour, 61 * time.Minute, true},
+		{"lazy only", 0, 10 * time.Minute, 0, 24 * time.Hour, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.Interval = Duration(test.interval)
+			config.MaxAge = Duration(test.maxAge)
+			config.ReadyMaxAge = Duration(test.readyMaxAge)
+			if got := feedStale(updated, updated.Add(test.age)); got != test.want {
+				t.Errorf("feedStale() after %s = %v, want %v", test.age, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package okorss
+
+import (
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+const htmlDefaultTemplate = `<!DOCTYPE html>
+<html lang="pl">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} – najnowsze artykuły</title>
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: auto; padding: 0 16px; color: #222; }
+.item { display: flex; gap: 16px; margin: 24px 0; }
+.item img { width: 160px; height: 100px; object-fit: cover; flex-shrink: 0; }
+.item h2 { font-size: 1.1em; margin: 0 0 4px; }
+small { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><small>Zaktualizowano {{.Updated.Format "02.01.2006 15:04"}} · <a href="{{.FeedUrl}}">kanał RSS</a></small></p>
+{{range .Items}}
+<div class="item">
+{{if .Enclosure.Url}}<a href="{{.Link}}"><img src="{{.Enclosure.Url}}" alt="" loading="lazy"></a>{{end}}
+<div>
+<h2><a href="{{.Link}}">{{.Title}}</a></h2>
+<small>{{.PubDate}}</small>
+</div>
+</div>
+{{end}}
+</body>
+</html>
+`
+
+// Template of the /html page, html_template in config replaces the built in one
+func loadHtmlTemplate() (*template.Template, error) {
+	source := htmlDefaultTemplate
+	if config.HtmlTemplate != "" {
+		data, err := os.ReadFile(config.HtmlTemplate)
+		if err != nil {
+			return nil, err
+		}
+		source = string(data)
+	}
+	return template.New("html").Parse(source)
+}
+
+// Current feed as a plain web page, for quick checks and people without a reader
+func serveHtml(page *template.Template) http.HandlerFunc {
+
+	var rendered RenderedFeed
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		refreshIfStale()
+		if feedNotReady(w) {
+			return
+		}
+		rss, updated := currentFeed()
+
+		base := requestBaseUrl(r)
+		variant, err := rendered.get(base, updated, func(w io.Writer) error {
+			return page.Execute(w, map[string]interface{}{
+				"Title":   rss.Channel.Title,
+				"Updated": updated.In(time.Local),
+				"FeedUrl": base + mainFeedPath,
+				"Items":   rss.Channel.Item,
+			})
+		})
+		if err != nil {
+			slog.Error("Error while rendering page", "path", r.URL.Path, "error", err)
+			http.Error(w, "page unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		variant.write(w, r, updated)
+	}
+}
@@ -0,0 +1,135 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// CSS selectors for a listing page. Except for item they're relative to
+// the item element, empty meaning the item itself. A value is taken from
+// the text unless an attribute follows the selector, e.g. "img@data-src"
+type HtmlSelectors struct {
+	Item  string `json:"item"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	Date  string `json:"date"`
+	Image string `json:"image"`
+	Lead  string `json:"lead"`
+}
+
+// Articles scraped from a listing page, for sites without an API or as
+// a fallback when the API breaks
+type HtmlSource struct {
+	config SourceConfig
+	base   *url.URL
+}
+
+func newHtmlSource(config Config) (Source, error) {
+
+	source := config.Source
+	if source.Url == "" {
+		return nil, fmt.Errorf("source url is required for the html source")
+	}
+	if source.Selectors.Item == "" || source.Selectors.Title == "" {
+		return nil, fmt.Errorf("item and title selectors are required for the html source")
+	}
+	if source.DateFormat == "" {
+		source.DateFormat = time.RFC3339
+	}
+	base, err := url.Parse(source.Url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing source url: %w", err)
+	}
+	return HtmlSource{config: source, base: base}, nil
+}
+
+func (source HtmlSource) Name() string {
+	return "HTML " + source.config.Url
+}
+
+func (source HtmlSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	body, err := fetchBody(ctx, source.config.Url, source.config.Headers)
+	if err != nil {
+		return nil, err
+	}
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("parsing page: %w", err)}
+	}
+
+	var nodes []Node
+	document.Find(source.config.Selectors.Item).Each(func(i int, item *goquery.Selection) {
+		node := source.node(item)
+		if node.Title != "" && node.Link != "" {
+			nodes = append(nodes, node)
+		}
+	})
+	if len(nodes) == 0 {
+		return nil, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("no items matched selector %q", source.config.Selectors.Item)}
+	}
+	return nodes, nil
+}
+
+// Value of the "selector@attribute" spec within the item, or of the
+// default attribute when given and present
+func selectValue(item *goquery.Selection, spec string, defaultAttribute string) string {
+
+	selector, attribute, _ := strings.Cut(spec, "@")
+	selection := item
+	if selector != "" {
+		selection = item.Find(selector).First()
+	}
+	if attribute == "" {
+		attribute = defaultAttribute
+	}
+	if attribute != "" {
+		value, ok := selection.Attr(attribute)
+		if ok || defaultAttribute == "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return strings.Join(strings.Fields(selection.Text()), " ")
+}
+
+// Links on the page are often relative to it
+func (source HtmlSource) absolute(link string) string {
+	if link == "" {
+		return ""
+	}
+	parsed, err := source.base.Parse(link)
+	if err != nil {
+		return link
+	}
+	return parsed.String()
+}
+
+func (source HtmlSource) node(item *goquery.Selection) Node {
+
+	selectors := source.config.Selectors
+
+	var node Node
+	node.Title = selectValue(item, selectors.Title, "")
+	node.Link = source.absolute(selectValue(item, selectors.Link, "href"))
+	node.ID = node.Link
+	if selectors.Lead != "" {
+		node.Lead = selectValue(item, selectors.Lead, "")
+	}
+	if selectors.Image != "" {
+		node.Image.Url = source.absolute(selectValue(item, selectors.Image, "src"))
+	}
+	if selectors.Date != "" {
+		published, err := time.Parse(source.config.DateFormat, selectValue(item, selectors.Date, "datetime"))
+		if err == nil {
+			node.Published = apiTime(published)
+		}
+	}
+	return node
+}
@@ -0,0 +1,93 @@
+package okorss
+
+import (
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Lead as a paragraph, nothing for articles without one. Leads may carry
+// upstream HTML, which is kept once sanitized
+const itemDefaultTemplate = `{{if .Lead}}<p>{{sanitize .Lead}}</p>{{end}}`
+
+// Text helpers of title templates, plus sanitize for upstream HTML
+var itemFuncs = func() template.FuncMap {
+	funcs := template.FuncMap{"sanitize": sanitizeTemplateHtml}
+	for name, function := range titleFuncs {
+		funcs[name] = function
+	}
+	return funcs
+}()
+
+// Renders item descriptions, item_template in config replaces the default
+var itemTemplate = template.Must(template.New("item").Funcs(itemFuncs).Parse(itemDefaultTemplate))
+
+// Whether the template refers to .Fields, decoding every article's JSON
+// again is skipped for the ones which don't
+var itemTemplateFields = false
+
+// Template sees the fields below, and all upstream fields of the article
+// under .Fields, e.g. "{{if .Image}}<img src=\"{{.Image}}\">{{end}}<p>{{.Lead}}</p>"
+type ItemTemplateData struct {
+	Title      string
+	Lead       string
+	Link       string
+	Image      string
+	Published  time.Time
+	Author     string
+	Authors    []string
+	Categories []string
+	Fields     map[string]interface{}
+}
+
+func setupItemTemplate(source string) error {
+	if source == "" {
+		return nil
+	}
+	parsed, err := template.New("item").Funcs(itemFuncs).Parse(source)
+	if err != nil {
+		return err
+	}
+	itemTemplate = parsed
+	itemTemplateFields = strings.Contains(source, ".Fields")
+	return nil
+}
+
+// HTML description of an item, empty when the template renders nothing.
+// The result is sanitized as well, in case the template itself lets
+// unsafe markup through
+func itemDescription(node Node, link string) string {
+
+	data := ItemTemplateData{
+		Title:     node.Title,
+		Lead:      node.Lead,
+		Link:      link,
+		Published: nodeTime(node),
+	}
+	if node.Image.Url != "" {
+		data.Image = nodeImage(node)
+	}
+	data.Authors = make([]string, 0, len(node.Authors))
+	for _, author := range node.Authors {
+		data.Authors = append(data.Authors, author.Name)
+	}
+	data.Author = strings.Join(data.Authors, ", ")
+	data.Categories = make([]string, 0, len(node.Categories))
+	for _, category := range node.Categories {
+		data.Categories = append(data.Categories, category.Name)
+	}
+	if node.Raw != nil && itemTemplateFields {
+		json.Unmarshal(node.Raw, &data.Fields)
+	}
+
+	description := getBuffer()
+	defer putBuffer(description)
+	err := itemTemplate.Execute(description, data)
+	if err != nil {
+		slog.Warn("Error while applying item template", "id", node.ID, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(sanitizeHtml(description.String()))
+}
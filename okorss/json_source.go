@@ -0,0 +1,111 @@
+package okorss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Paths to item fields in gjson syntax, see
+// https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+type JsonFields struct {
+	Id         string `json:"id"`
+	Title      string `json:"title"`
+	Link       string `json:"link"`
+	Date       string `json:"date"`
+	Image      string `json:"image"`
+	Lead       string `json:"lead"`
+	Categories string `json:"categories"`
+}
+
+// Any JSON API, mapped onto articles by paths given in config
+type JsonSource struct {
+	config SourceConfig
+}
+
+func newJsonSource(config Config) (Source, error) {
+
+	source := config.Source
+	if source.Url == "" {
+		return nil, fmt.Errorf("source url is required for the json source")
+	}
+	if source.Fields.Title == "" || source.Fields.Link == "" {
+		return nil, fmt.Errorf("title and link fields are required for the json source")
+	}
+	if source.DateFormat == "" {
+		source.DateFormat = time.RFC3339
+	}
+	return JsonSource{config: source}, nil
+}
+
+func (source JsonSource) Name() string {
+	return "JSON " + source.config.Url
+}
+
+func (source JsonSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	body, err := fetchBody(ctx, source.config.Url, source.config.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("response is not valid JSON")}
+	}
+
+	// Whole document is the item list unless a path is given
+	items := gjson.ParseBytes(body)
+	if source.config.Items != "" {
+		items = items.Get(source.config.Items)
+	}
+	if !items.IsArray() {
+		return nil, fmt.Errorf("items path %q doesn't point to an array", source.config.Items)
+	}
+
+	var nodes []Node
+	for _, item := range items.Array() {
+		nodes = append(nodes, source.node(item))
+	}
+	return nodes, nil
+}
+
+func (source JsonSource) node(item gjson.Result) Node {
+
+	fields := source.config.Fields
+	get := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		return item.Get(path).String()
+	}
+
+	var node Node
+	node.Title = get(fields.Title)
+	node.Link = get(fields.Link)
+	node.Lead = get(fields.Lead)
+	node.Image.Url = get(fields.Image)
+	node.ID = get(fields.Id)
+	if node.ID == "" {
+		node.ID = node.Link
+	}
+
+	published, err := time.Parse(source.config.DateFormat, get(fields.Date))
+	if err == nil {
+		node.Published = apiTime(published)
+	}
+
+	// Categories are either names or objects with a name
+	if fields.Categories != "" {
+		for _, category := range item.Get(fields.Categories).Array() {
+			name := category.String()
+			if category.IsObject() {
+				name = category.Get("name").String()
+			}
+			node.Categories = append(node.Categories, Category{Name: name})
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,72 @@
+//go:build lambda
+
+package okorss
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Serve Lambda function URL and API Gateway HTTP API invocations, which
+// share the 2.0 payload format, with the regular handler
+func startLambda(handler http.Handler) {
+	lambda.Start(func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+
+		url := event.RawPath
+		if event.RawQueryString != "" {
+			url += "?" + event.RawQueryString
+		}
+		body := event.Body
+		if event.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(body)
+			if err != nil {
+				return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusBadRequest}, nil
+			}
+			body = string(decoded)
+		}
+
+		request, err := http.NewRequestWithContext(ctx, event.RequestContext.HTTP.Method, url, strings.NewReader(body))
+		if err != nil {
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusBadRequest}, nil
+		}
+		for name, value := range event.Headers {
+			request.Header.Set(name, value)
+		}
+		request.Host = event.RequestContext.DomainName
+		request.RemoteAddr = event.RequestContext.HTTP.SourceIP + ":0"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		response := events.APIGatewayV2HTTPResponse{
+			StatusCode: recorder.Code,
+			Headers:    make(map[string]string),
+			Body:       recorder.Body.String(),
+		}
+		for name, values := range recorder.Header() {
+			response.Headers[name] = strings.Join(values, ", ")
+		}
+		// Compressed feeds and images would be mangled as text
+		if recorder.Header().Get("Content-Encoding") != "" || !textContentType(recorder.Header().Get("Content-Type")) {
+			response.Body = base64.StdEncoding.EncodeToString(recorder.Body.Bytes())
+			response.IsBase64Encoded = true
+		}
+		return response, nil
+	})
+}
+
+// Whether responses of the type can be passed on as a string, like feeds,
+// pages and JSON. Responses without a body have no type and are too
+func textContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json") ||
+		mediaType == "application/javascript"
+}
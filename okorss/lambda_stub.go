@@ -0,0 +1,11 @@
+//go:build !lambda
+
+package okorss
+
+import "net/http"
+
+// Lambda support pulls in the AWS runtime client, so it's built only with
+// "go build -tags lambda"
+func startLambda(handler http.Handler) {
+	fatal("Built without AWS Lambda support, rebuild with -tags lambda")
+}
@@ -0,0 +1,76 @@
+package okorss
+
+import (
+	"bytes"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Where the main feed is served, / being the landing page
+const mainFeedPath = "/rss.xml"
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="pl">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>OKO.press – kanały RSS</title>
+{{range .Feeds}}<link rel="alternate" type="application/rss+xml" title="{{.Title}}" href="{{.Url}}">
+{{end -}}
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: auto; padding: 0 16px; color: #222; }
+code { background: #f3f3f3; padding: 2px 4px; }
+</style>
+</head>
+<body>
+<h1>OKO.press – kanały RSS</h1>
+<p>Kanały z artykułami OKO.press. Skopiuj adres kanału do czytnika RSS, albo podaj mu adres tej strony, a sam je znajdzie.</p>
+<ul>
+{{range .Feeds}}<li><a href="{{.Url}}">{{.Title}}</a> – <code>{{.Url}}</code></li>
+{{end -}}
+</ul>
+<p>Wszystkie kanały naraz do zaimportowania: <a href="{{.Opml}}">feeds.opml</a>. Najnowsze artykuły bez czytnika: <a href="{{.Html}}">wersja HTML</a>.</p>
+</body>
+</html>
+`))
+
+// Browsers get a page listing the feeds, with links for autodiscovery.
+// Readers still subscribed to / are sent on to the feed
+func serveLanding(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Vary", "Accept")
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		target := requestBasePath(r) + mainFeedPath
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	base := requestBaseUrl(r)
+	type landingFeed struct {
+		Title string
+		Url   string
+	}
+	var feeds []landingFeed
+	for _, feed := range feedRoutes {
+		feeds = append(feeds, landingFeed{Title: feed.Title, Url: base + feed.Path})
+	}
+
+	var body bytes.Buffer
+	err := landingTemplate.Execute(&body, map[string]interface{}{
+		"Feeds": feeds,
+		"Opml":  base + "/feeds.opml",
+		"Html":  base + "/html",
+	})
+	if err != nil {
+		slog.Error("Error while rendering page", "path", r.URL.Path, "error", err)
+		http.Error(w, "page unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,47 @@
+package okorss
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Set while a lazy refresh runs, so concurrent requests start only one
+var lazyRefreshing atomic.Bool
+var lazyRefreshes sync.WaitGroup
+
+// Without interval or schedule the feed is refreshed only when requested
+// after max_age, which suits rarely polled instances
+func lazyOnly() bool {
+	return config.MaxAge > 0 && config.Interval == 0 && refreshSchedule == nil
+}
+
+// Refresh in the background when the feed is older than max_age. The
+// request is served the current copy right away. Failed attempts are
+// retried no sooner than max_age later
+func refreshIfStale() {
+
+	if config.MaxAge <= 0 {
+		return
+	}
+	maxAge := time.Duration(config.MaxAge)
+	_, updated := currentFeed()
+	if time.Since(updated) < maxAge || time.Since(lastFetchTime()) < maxAge {
+		return
+	}
+	if !lazyRefreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	lazyRefreshes.Add(1)
+	go func() {
+		defer lazyRefreshes.Done()
+		defer lazyRefreshing.Store(false)
+		defer reportPanic()
+		slog.Info("Feed is stale, refreshing", "feed", config.Url, "age", time.Since(updated))
+		_, err := refresh(context.Background())
+		logRefreshError(err)
+	}()
+}
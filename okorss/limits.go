@@ -0,0 +1,73 @@
+package okorss
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/netutil"
+)
+
+// Limits keeping slow or greedy clients from tying up small instances,
+// zero takes the default. Long polls extend the write timeout for
+// themselves and WebSockets aren't subject to it once upgraded
+type ServerLimitsConfig struct {
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	MaxHeaderBytes    int      `json:"max_header_bytes"`
+	// Connections accepted at once, WebSockets included. Further clients
+	// wait to be accepted
+	MaxConnections int `json:"max_connections"`
+}
+
+func (limits ServerLimitsConfig) readHeaderTimeout() time.Duration {
+	if limits.ReadHeaderTimeout > 0 {
+		return time.Duration(limits.ReadHeaderTimeout)
+	}
+	return 10 * time.Second
+}
+
+func (limits ServerLimitsConfig) writeTimeout() time.Duration {
+	if limits.WriteTimeout > 0 {
+		return time.Duration(limits.WriteTimeout)
+	}
+	return time.Minute
+}
+
+func (limits ServerLimitsConfig) idleTimeout() time.Duration {
+	if limits.IdleTimeout > 0 {
+		return time.Duration(limits.IdleTimeout)
+	}
+	return 2 * time.Minute
+}
+
+func (limits ServerLimitsConfig) maxHeaderBytes() int {
+	if limits.MaxHeaderBytes > 0 {
+		return limits.MaxHeaderBytes
+	}
+	return 64 << 10
+}
+
+// Above the default WebSocket client limit, so they can't use all of them
+func (limits ServerLimitsConfig) maxConnections() int {
+	if limits.MaxConnections > 0 {
+		return limits.MaxConnections
+	}
+	return 2048
+}
+
+// Server for the handler with the configured timeouts and header limit
+func limitedServer(handler http.Handler, limits ServerLimitsConfig) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: limits.readHeaderTimeout(),
+		WriteTimeout:      limits.writeTimeout(),
+		IdleTimeout:       limits.idleTimeout(),
+		MaxHeaderBytes:    limits.maxHeaderBytes(),
+	}
+}
+
+func limitConnections(listener net.Listener, limits ServerLimitsConfig) net.Listener {
+	return netutil.LimitListener(listener, limits.maxConnections())
+}
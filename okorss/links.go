@@ -0,0 +1,118 @@
+package okorss
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Query parameters added to or removed from item links in feeds
+type LinkConfig struct {
+	Append map[string]string `json:"append"`
+	Strip  []string          `json:"strip"`
+}
+
+// Remove parameters matching any strip pattern, e.g. "utm_*" or "fbclid",
+// then add the configured ones, e.g. {"utm_source": "rss"}
+func rewriteLink(link string) string {
+
+	if len(config.Links.Append) == 0 && len(config.Links.Strip) == 0 {
+		return link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	query := parsed.Query()
+	for name := range query {
+		for _, pattern := range config.Links.Strip {
+			matched, _ := path.Match(pattern, name)
+			if matched {
+				query.Del(name)
+				break
+			}
+		}
+	}
+	for name, value := range config.Links.Append {
+		query.Set(name, value)
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// Feeds identify items by article ID by default. With permalink set they
+// use the article URL instead. Readers treat a changed GUID as a new item,
+// so articles published before permalink_since ("YYYY-MM-DD") keep their
+// ID GUIDs and only newer ones switch
+//
+// Namespaced GUIDs are opt in for the same reason: only articles published
+// from namespaced_since ("YYYY-MM-DD") get them, older ones and all of them
+// without the date keep bare IDs
+type GuidConfig struct {
+	Permalink       bool   `json:"permalink"`
+	PermalinkSince  string `json:"permalink_since"`
+	NamespacedSince string `json:"namespaced_since"`
+
+	since           time.Time
+	namespacedSince time.Time
+}
+
+func (guid *GuidConfig) Setup() error {
+	var err error
+	guid.since, err = guidSince("permalink_since", guid.PermalinkSince)
+	if err != nil {
+		return err
+	}
+	guid.namespacedSince, err = guidSince("namespaced_since", guid.NamespacedSince)
+	return err
+}
+
+func guidSince(name string, date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.ParseInLocation("2006-01-02", date, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD", name, date)
+	}
+	return since, nil
+}
+
+const guidNamespace = "oko-press-rss"
+
+// GUID as "oko-press-rss:<source>:<id>", e.g. "oko-press-rss:okopress:123".
+// The same upstream article always gets the same GUID, whatever happens to
+// its title, link or dates, and articles of different sources never share
+// one. The source is the label in aggregated feeds and the source type
+// otherwise, so relabelling a source changes the GUIDs of its articles
+func namespacedGuid(node Node) string {
+	source, id := node.Source, node.ID
+	if source != "" {
+		id = strings.TrimPrefix(id, source+"/")
+	} else {
+		source = config.Source.Type
+		if source == "" {
+			source = "okopress"
+		}
+	}
+	return guidNamespace + ":" + source + ":" + id
+}
+
+// GUID of item in feeds and whether it's a permalink. Permalinks are the
+// item's link, the pipeline updates them once it has the final one.
+// Internal bookkeeping always uses nodeGuid, so switching modes doesn't
+// renotify anything
+func itemGuid(node Node) (string, bool) {
+	published := nodeTime(node)
+	if config.Guid.Permalink && !published.Before(config.Guid.since) {
+		return nodeLink(node), true
+	}
+	if config.Guid.namespacedSince.IsZero() || published.Before(config.Guid.namespacedSince) {
+		return nodeGuid(node), false
+	}
+	return namespacedGuid(node), false
+}
@@ -0,0 +1,46 @@
+package okorss
+
+import "testing"
+
+func TestItemGuid(t *testing.T) {
+
+	defer func(saved Config) { config = saved }(config)
+
+	old := Node{ID: "1", Published: "2024-05-01T10:00:00"}
+	old.SeoFields.Slug = "stary"
+	recent := Node{ID: "2", Published: "2025-05-01T10:00:00"}
+	recent.SeoFields.Slug = "nowy"
+	labelled := Node{ID: "rss/3", Source: "rss", Published: "2025-05-01T10:00:00", Link: "https://example.com/3"}
+
+	tests := []struct {
+		name      string
+		guid      GuidConfig
+		node      Node
+		want      string
+		permalink bool
+	}{
+		{"default", GuidConfig{}, recent, "2", false},
+		{"permalink", GuidConfig{Permalink: true}, recent, "https://oko.press/nowy", true},
+		{"permalink own link", GuidConfig{Permalink: true}, labelled, "https://example.com/3", true},
+		{"permalink before since", GuidConfig{Permalink: true, PermalinkSince: "2025-01-01"}, old, "1", false},
+		{"permalink after since", GuidConfig{Permalink: true, PermalinkSince: "2025-01-01"}, recent, "https://oko.press/nowy", true},
+		{"namespaced before since", GuidConfig{NamespacedSince: "2025-01-01"}, old, "1", false},
+		{"namespaced after since", GuidConfig{NamespacedSince: "2025-01-01"}, recent, "oko-press-rss:okopress:2", false},
+		{"namespaced with source", GuidConfig{NamespacedSince: "2025-01-01"}, labelled, "oko-press-rss:rss:3", false},
+		{"permalink wins over namespaced", GuidConfig{Permalink: true, NamespacedSince: "2025-01-01"}, recent, "https://oko.press/nowy", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.Guid = test.guid
+			err := config.Guid.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			guid, permalink := itemGuid(test.node)
+			if guid != test.want || permalink != test.permalink {
+				t.Errorf("itemGuid() = %q, %v, want %q, %v", guid, permalink, test.want, test.permalink)
+			}
+		})
+	}
+}
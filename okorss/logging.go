@@ -0,0 +1,61 @@
+package okorss
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Overrides log_level from config when given on the command line
+var logLevelFlag string
+
+// Replace the default logger according to config. Level is one of debug,
+// info (default), warn or error. Format is "text" (default) or "json",
+// output is "stderr" (default), "stdout" or a file path, which is appended to
+func setupLogging(level string, format string, output string) error {
+
+	var options slog.HandlerOptions
+	if level != "" {
+		var parsed slog.Level
+		err := parsed.UnmarshalText([]byte(level))
+		if err != nil {
+			return fmt.Errorf("unknown log level %q", level)
+		}
+		options.Level = parsed
+	}
+
+	var writer io.Writer
+	switch output {
+	case "", "stderr":
+		writer = os.Stderr
+	case "stdout":
+		writer = os.Stdout
+	default:
+		file, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		writer = file
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "", "text":
+		handler = slog.NewTextHandler(writer, &options)
+	case "json":
+		handler = slog.NewJSONHandler(writer, &options)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	// Also routes the standard logger, e.g. net/http errors
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Log an unrecoverable error and exit
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
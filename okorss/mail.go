@@ -0,0 +1,109 @@
+package okorss
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mail server and envelope, shared by everything sending email
+type SmtpConfig struct {
+	SmtpHost string   `json:"smtp_host"`
+	SmtpPort int      `json:"smtp_port"`
+	SmtpTls  bool     `json:"smtp_tls"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+func mailMessage(config SmtpConfig, subject string, contentType string, body []byte) []byte {
+
+	var message bytes.Buffer
+	header := func(name string, value string) {
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", config.From)
+	header("To", strings.Join(config.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", contentType+"; charset=utf-8")
+	header("Content-Transfer-Encoding", "base64")
+	message.WriteString("\r\n")
+
+	// Wrap base64 body at 76 characters as required by RFC 2045
+	encoded := base64.StdEncoding.EncodeToString(body)
+	for len(encoded) > 76 {
+		message.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	message.WriteString(encoded + "\r\n")
+
+	return message.Bytes()
+}
+
+func sendMail(config SmtpConfig, message []byte) error {
+
+	port := config.SmtpPort
+	if port == 0 {
+		port = 587
+	}
+	address := net.JoinHostPort(config.SmtpHost, strconv.Itoa(port))
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.SmtpHost)
+	}
+
+	// SendMail upgrades with STARTTLS itself, implicit TLS needs own client
+	if !config.SmtpTls {
+		return smtp.SendMail(address, auth, config.From, config.To, message)
+	}
+
+	connection, err := tls.Dial("tcp", address, &tls.Config{ServerName: config.SmtpHost})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(connection, config.SmtpHost)
+	if err != nil {
+		connection.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		err = client.Auth(auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(config.From)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range config.To {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,144 @@
+package okorss
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const mastodonDefaultTemplate = `{{.Title}}
+
+{{.Link}}`
+
+type MastodonConfig struct {
+	Enabled         bool     `json:"enabled"`
+	InstanceUrl     string   `json:"instance_url"`
+	AccessToken     string   `json:"access_token"`
+	Visibility      string   `json:"visibility"`
+	Template        string   `json:"template"`
+	AttachThumbnail bool     `json:"attach_thumbnail"`
+	Delay           Duration `json:"delay"`
+}
+
+type MastodonNotifier struct {
+	config   MastodonConfig
+	template *template.Template
+}
+
+func newMastodonNotifier(config MastodonConfig) (*MastodonNotifier, error) {
+
+	config.InstanceUrl = strings.TrimSuffix(config.InstanceUrl, "/")
+	if config.Template == "" {
+		config.Template = mastodonDefaultTemplate
+	}
+	if config.Visibility == "" {
+		config.Visibility = "public"
+	}
+
+	parsed, err := template.New("mastodon").Parse(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MastodonNotifier{config: config, template: parsed}, nil
+}
+
+func (mastodon *MastodonNotifier) Name() string {
+	return "Mastodon " + mastodon.config.InstanceUrl
+}
+
+func (mastodon *MastodonNotifier) authorization() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + mastodon.config.AccessToken}
+}
+
+// Upload thumbnail and return its media ID
+func (mastodon *MastodonNotifier) uploadThumbnail(item NotifyItem) (string, error) {
+
+	image, contentType, err := downloadImage(item.Image)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("description", item.Title)
+	header := make(map[string][]string)
+	header["Content-Disposition"] = []string{`form-data; name="file"; filename="thumbnail"`}
+	header["Content-Type"] = []string{contentType}
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	part.Write(image)
+	form.Close()
+
+	request, err := http.NewRequest("POST", mastodon.config.InstanceUrl+"/api/v2/media", &body)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("Authorization", "Bearer "+mastodon.config.AccessToken)
+
+	var media struct {
+		ID string `json:"id"`
+	}
+	err = doRequest(request, &media)
+	return media.ID, err
+}
+
+func (mastodon *MastodonNotifier) Notify(nodes []Node) error {
+
+	failed := 0
+	for i, node := range nodes {
+		if i > 0 && mastodon.config.Delay > 0 {
+			time.Sleep(time.Duration(mastodon.config.Delay))
+		}
+		err := mastodon.post(node)
+		if err != nil {
+			slog.Warn("Error while posting Mastodon status", "id", node.ID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d statuses failed", failed, len(nodes))
+	}
+	return nil
+}
+
+func (mastodon *MastodonNotifier) post(node Node) error {
+
+	item := newNotifyItem(node)
+	var text bytes.Buffer
+	err := mastodon.template.Execute(&text, item)
+	if err != nil {
+		return err
+	}
+
+	status := map[string]interface{}{
+		"status":     text.String(),
+		"visibility": mastodon.config.Visibility,
+	}
+
+	// Post without thumbnail rather than not at all
+	if mastodon.config.AttachThumbnail && item.Image != "" {
+		mediaID, err := mastodon.uploadThumbnail(item)
+		if err != nil {
+			slog.Warn("Error while uploading thumbnail", "notifier", mastodon.Name(), "error", err)
+		} else {
+			status["media_ids"] = []string{mediaID}
+		}
+	}
+
+	// Idempotency key makes sure retries never post the same item twice
+	headers := mastodon.authorization()
+	headers["Idempotency-Key"] = nodeGuid(node)
+	return retry(3, 5*time.Second, func() error {
+		return sendJson("POST", mastodon.config.InstanceUrl+"/api/v1/statuses", headers, status, nil)
+	})
+}
@@ -0,0 +1,58 @@
+package okorss
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type MatrixConfig struct {
+	Enabled     bool   `json:"enabled"`
+	Homeserver  string `json:"homeserver"`
+	AccessToken string `json:"access_token"`
+	RoomID      string `json:"room_id"`
+}
+
+type MatrixNotifier struct {
+	config MatrixConfig
+}
+
+func (matrix MatrixNotifier) Name() string {
+	return "Matrix " + matrix.config.RoomID
+}
+
+func (matrix MatrixNotifier) Notify(nodes []Node) error {
+
+	homeserver := strings.TrimSuffix(matrix.config.Homeserver, "/")
+	headers := map[string]string{"Authorization": "Bearer " + matrix.config.AccessToken}
+
+	for _, node := range nodes {
+		item := newNotifyItem(node)
+
+		plain := item.Title + "\n" + item.Link
+		formatted := `<a href="` + html.EscapeString(item.Link) + `"><strong>` + html.EscapeString(item.Title) + `</strong></a>`
+		if item.Lead != "" {
+			plain += "\n\n" + item.Lead
+			formatted += "<br>" + html.EscapeString(item.Lead)
+		}
+		message := map[string]string{
+			"msgtype":        "m.notice",
+			"body":           plain,
+			"format":         "org.matrix.custom.html",
+			"formatted_body": formatted,
+		}
+
+		// The server ignores repeated transaction IDs, which makes retries safe
+		transaction := url.PathEscape("oko-rss-" + nodeGuid(node))
+		endpoint := homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(matrix.config.RoomID) + "/send/m.room.message/" + transaction
+
+		err := retry(3, 5*time.Second, func() error {
+			return sendJson("PUT", endpoint, headers, message, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package okorss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the fetch duration histogram, in seconds
+var fetchDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Process wide metrics in Prometheus text exposition format. Kept small
+// and hand written, the few metrics here don't justify a client library
+var metrics struct {
+	sync.Mutex
+	fetchAttempts       float64
+	fetchFailures       float64
+	fetchSoftFailures   float64
+	fetchDurationCounts []float64
+	fetchDurationSum    float64
+	fetchDurationCount  float64
+	feedItems           float64
+	newItems            float64
+	lastNewItems        float64
+	httpRequests        map[httpRequestLabels]float64
+}
+
+type httpRequestLabels struct {
+	path   string
+	status int
+}
+
+func observeFetch(duration time.Duration, err error) {
+
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	metrics.fetchAttempts++
+	var tooFew *TooFewItemsError
+	if errors.As(err, &tooFew) {
+		metrics.fetchSoftFailures++
+	} else if err != nil {
+		metrics.fetchFailures++
+	}
+
+	if metrics.fetchDurationCounts == nil {
+		metrics.fetchDurationCounts = make([]float64, len(fetchDurationBuckets))
+	}
+	seconds := duration.Seconds()
+	for i, bound := range fetchDurationBuckets {
+		if seconds <= bound {
+			metrics.fetchDurationCounts[i]++
+		}
+	}
+	metrics.fetchDurationSum += seconds
+	metrics.fetchDurationCount++
+}
+
+func observeRefresh(items int, newItems int) {
+	metrics.Lock()
+	metrics.feedItems = float64(items)
+	metrics.newItems += float64(newItems)
+	metrics.lastNewItems = float64(newItems)
+	metrics.Unlock()
+}
+
+func observeRequest(path string, status int) {
+	metrics.Lock()
+	if metrics.httpRequests == nil {
+		metrics.httpRequests = make(map[httpRequestLabels]float64)
+	}
+	metrics.httpRequests[httpRequestLabels{path, status}]++
+	metrics.Unlock()
+}
+
+// Count requests per route, labelled with the route rather than the
+// requested path to keep the number of series bounded
+func withMetrics(path string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w}
+		handler(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		observeRequest(path, recorder.status)
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Metrics are formatted into a buffer, so a slow scraper doesn't hold the
+// lock requests record metrics under
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+
+	_, updated := currentFeed()
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	metrics.Lock()
+	writeMetrics(buffer, updated)
+	metrics.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buffer.Bytes())
+}
+
+// Caller holds the lock
+func writeMetrics(w io.Writer, updated time.Time) {
+
+	metric := func(name string, kind string, help string, value float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatFloat(value))
+	}
+
+	metric("okorss_fetch_attempts_total", "counter", "Upstream fetches attempted.", metrics.fetchAttempts)
+	metric("okorss_fetch_failures_total", "counter", "Upstream fetches that failed.", metrics.fetchFailures)
+	metric("okorss_fetch_soft_failures_total", "counter", "Upstream fetches with too few articles to publish.", metrics.fetchSoftFailures)
+
+	fmt.Fprintf(w, "# HELP okorss_fetch_duration_seconds Time taken by upstream fetches.\n# TYPE okorss_fetch_duration_seconds histogram\n")
+	for i, bound := range fetchDurationBuckets {
+		count := 0.0
+		if metrics.fetchDurationCounts != nil {
+			count = metrics.fetchDurationCounts[i]
+		}
+		fmt.Fprintf(w, "okorss_fetch_duration_seconds_bucket{le=\"%s\"} %s\n", formatFloat(bound), formatFloat(count))
+	}
+	fmt.Fprintf(w, "okorss_fetch_duration_seconds_bucket{le=\"+Inf\"} %s\n", formatFloat(metrics.fetchDurationCount))
+	fmt.Fprintf(w, "okorss_fetch_duration_seconds_sum %s\n", formatFloat(metrics.fetchDurationSum))
+	fmt.Fprintf(w, "okorss_fetch_duration_seconds_count %s\n", formatFloat(metrics.fetchDurationCount))
+
+	metric("okorss_feed_items", "gauge", "Items in the current feed.", metrics.feedItems)
+	metric("okorss_new_items_total", "counter", "New items discovered by refreshes.", metrics.newItems)
+	metric("okorss_last_refresh_new_items", "gauge", "New items discovered by the latest refresh.", metrics.lastNewItems)
+
+	age := 0.0
+	if !updated.IsZero() {
+		age = time.Since(updated).Seconds()
+	}
+	metric("okorss_feed_age_seconds", "gauge", "Seconds since the feed was last generated.", age)
+
+	// Sort series so output is stable between scrapes
+	var labels []httpRequestLabels
+	for label := range metrics.httpRequests {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].path != labels[j].path {
+			return labels[i].path < labels[j].path
+		}
+		return labels[i].status < labels[j].status
+	})
+	fmt.Fprintf(w, "# HELP okorss_http_requests_total HTTP requests served, by route and status.\n# TYPE okorss_http_requests_total counter\n")
+	for _, label := range labels {
+		fmt.Fprintf(w, "okorss_http_requests_total{path=%q,status=\"%d\"} %s\n", label.path, label.status, formatFloat(metrics.httpRequests[label]))
+	}
+}
@@ -0,0 +1,92 @@
+package okorss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Several sources merged into one feed, newest first. Items are labelled
+// with their source, which also namespaces their IDs
+type MultiSource struct {
+	sources []Source
+	configs []SourceConfig
+}
+
+func newMultiSource(config Config) (Source, error) {
+
+	if len(config.Source.Sources) == 0 {
+		return nil, fmt.Errorf("sources are required for the multi source")
+	}
+
+	multi := MultiSource{}
+	labels := make(map[string]bool)
+	for i, sourceConfig := range config.Source.Sources {
+		if sourceConfig.Label != "" && labels[sourceConfig.Label] {
+			return nil, fmt.Errorf("source %d: duplicate label %q", i+1, sourceConfig.Label)
+		}
+		labels[sourceConfig.Label] = true
+
+		childConfig := config
+		childConfig.Source = sourceConfig
+		child, err := newSource(childConfig)
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", i+1, err)
+		}
+		multi.sources = append(multi.sources, child)
+		multi.configs = append(multi.configs, sourceConfig)
+	}
+	return multi, nil
+}
+
+func (multi MultiSource) Name() string {
+	return fmt.Sprintf("%d sources", len(multi.sources))
+}
+
+// Fetch all sources at once. A failing source is left out, only when all
+// of them fail does the fetch
+func (multi MultiSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	results := make([][]Node, len(multi.sources))
+	errs := make([]error, len(multi.sources))
+	var wg sync.WaitGroup
+	for i, source := range multi.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			results[i], errs[i] = source.Fetch(ctx)
+		}(i, source)
+	}
+	wg.Wait()
+
+	var nodes []Node
+	failed := 0
+	for i, sourceNodes := range results {
+		config := multi.configs[i]
+		if errs[i] != nil {
+			slog.Warn("Error while fetching from aggregated source", "source", multi.sources[i].Name(), "label", config.Label, "error", errs[i])
+			failed++
+			continue
+		}
+
+		sortNodes(sourceNodes)
+		if config.MaxItems > 0 && len(sourceNodes) > config.MaxItems {
+			sourceNodes = sourceNodes[:config.MaxItems]
+		}
+		for _, node := range sourceNodes {
+			if config.Label != "" {
+				node.ID = config.Label + "/" + node.ID
+				node.Source = config.Label
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if failed == len(multi.sources) {
+		return nil, errors.Join(errs...)
+	}
+
+	sortNodes(nodes)
+	return nodes, nil
+}
@@ -0,0 +1,259 @@
+package okorss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Integration announcing new articles somewhere else
+type Notifier interface {
+	Name() string
+	Notify(nodes []Node) error
+}
+
+// Article fields available to notification message templates
+type NotifyItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Lead      string    `json:"lead"`
+	Link      string    `json:"link"`
+	Image     string    `json:"image"`
+	Published time.Time `json:"published"`
+}
+
+func newNotifyItem(node Node) NotifyItem {
+	return NotifyItem{
+		ID:        node.ID,
+		Title:     node.Title,
+		Lead:      node.Lead,
+		Link:      nodeLink(node),
+		Image:     nodeImage(node),
+		Published: nodeTime(node),
+	}
+}
+
+// Notification batches waiting per notifier, beyond this they are dropped
+const notifyQueueSize = 16
+
+// Shared client for all outgoing integration requests
+var notifyClient = &http.Client{Timeout: 30 * time.Second}
+
+var notifiers []Notifier
+var notifyQueues []chan []Node
+
+// Closed to stop notifier workers and schedulers, which are counted in
+// notifyWorkers
+var notifyStop = make(chan struct{})
+var notifyWorkers sync.WaitGroup
+
+// Build notifiers from config and start one worker per notifier, so a slow
+// or rate limited integration never holds back the feed or the others
+func startNotifiers() {
+
+	for _, webhook := range config.Webhooks {
+		notifiers = append(notifiers, webhook)
+	}
+	if config.Mastodon.Enabled {
+		mastodon, err := newMastodonNotifier(config.Mastodon)
+		if err != nil {
+			fatal("Error while setting up Mastodon", "error", err)
+		}
+		notifiers = append(notifiers, mastodon)
+	}
+	if config.Bluesky.Enabled {
+		notifiers = append(notifiers, newBlueskyNotifier(config.Bluesky))
+	}
+	if config.Discord.Enabled {
+		notifiers = append(notifiers, DiscordNotifier{config: config.Discord})
+	}
+	if config.Slack.Enabled {
+		slack, err := newSlackNotifier(config.Slack)
+		if err != nil {
+			fatal("Error while setting up Slack", "error", err)
+		}
+		notifiers = append(notifiers, slack)
+	}
+	if config.Ntfy.Enabled {
+		notifiers = append(notifiers, NtfyNotifier{config: config.Ntfy})
+	}
+	if config.Gotify.Enabled {
+		notifiers = append(notifiers, GotifyNotifier{config: config.Gotify})
+	}
+	if config.EmailDigest.Enabled {
+		digest, err := newEmailDigest(config.EmailDigest)
+		if err != nil {
+			fatal("Error while setting up email digest", "error", err)
+		}
+		notifiers = append(notifiers, digest)
+	}
+	if config.Cloud.Enabled {
+		notifiers = append(notifiers, CloudNotifier{})
+	}
+	if config.Matrix.Enabled {
+		notifiers = append(notifiers, MatrixNotifier{config: config.Matrix})
+	}
+	if config.Telegram.Enabled {
+		telegram, err := newTelegramNotifier(config.Telegram)
+		if err != nil {
+			fatal("Error while setting up Telegram", "error", err)
+		}
+		notifiers = append(notifiers, telegram)
+	}
+
+	for _, notifier := range notifiers {
+		queue := make(chan []Node, notifyQueueSize)
+		notifyQueues = append(notifyQueues, queue)
+		notifyWorkers.Add(1)
+		go notifyWorker(notifier, queue)
+	}
+}
+
+func notifyWorker(notifier Notifier, queue chan []Node) {
+	defer notifyWorkers.Done()
+	defer reportPanic()
+	for {
+		select {
+		case <-notifyStop:
+			return
+		case nodes := <-queue:
+			err := notifier.Notify(nodes)
+			if err != nil {
+				slog.Error("Error while notifying", "notifier", notifier.Name(), "error", err)
+			}
+		}
+	}
+}
+
+// Stop notifiers once batches being sent are done, queued ones are dropped
+func stopNotifiers() {
+	close(notifyStop)
+	notifyWorkers.Wait()
+}
+
+// Pass newly discovered articles to every notifier
+func notifyChanges(changes []ItemChange) {
+
+	var nodes []Node
+	for _, change := range changes {
+		if change.Change == ChangeNew {
+			nodes = append(nodes, change.Node)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	pushWebSockets(nodes)
+	recordPollItems(nodes)
+
+	for i, queue := range notifyQueues {
+		select {
+		case queue <- nodes:
+		default:
+			slog.Warn("Notification queue is full, dropping items", "notifier", notifiers[i].Name(), "items", len(nodes))
+		}
+	}
+}
+
+// Thumbnails bigger than this are not attached to posts
+const maxImageSize = 8 << 20
+
+// Download image for attaching to a post
+func downloadImage(url string) ([]byte, string, error) {
+
+	response, err := notifyClient.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("bad HTTP status: %s", response.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", fmt.Errorf("image larger than %d bytes", maxImageSize)
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
+
+// Call function until it succeeds, waiting longer after every failure
+func retry(attempts int, wait time.Duration, call func() error) error {
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		err = call()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Send JSON request and decode JSON response into result, when not nil
+func sendJson(method string, url string, headers map[string]string, payload interface{}, result interface{}) error {
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	return doRequest(request, result)
+}
+
+// Execute request and decode JSON response into result, when not nil
+func doRequest(request *http.Request, result interface{}) error {
+	return doRequestWith(notifyClient, request, result)
+}
+
+func doRequestWith(client *http.Client, request *http.Request, result interface{}) error {
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("bad HTTP status: %s, response: %s", response.Status, snippet)
+	}
+
+	if result == nil {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
@@ -0,0 +1,874 @@
+package okorss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"encoding/json"
+	"encoding/xml"
+	"time"
+	"os"
+	"flag"
+	"sync"
+	"os/signal"
+	"syscall"
+	"sort"
+	"html"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Nodes are decoded one by one, so one article upstream changed the shape
+// of doesn't take the whole response down
+type JsonResponse struct {
+	Data struct {
+		Nodes []json.RawMessage `json:"nodes"`
+	} `json:"data"`
+}
+
+type Node struct {
+	ID string `json:"id"`
+	Title string `json:"title"`
+	Lead string `json:"lead"`
+	Published string `json:"publish_at"`
+	SeoFields struct {
+		Slug string `json:"slug"`
+	} `json:"seo_fields"`
+	Image struct {
+		Url string `json:"original_url"`
+	} `json:"featured_image"`
+	Categories []Category `json:"categories"`
+	Authors []Author `json:"authors"`
+	// Set by sources whose links aren't derived from the slug
+	Link string `json:"link,omitempty"`
+	// Label of the source in aggregated feeds
+	Source string `json:"source,omitempty"`
+	// Served by a fallback source, see FallbackSource
+	Fallback bool `json:"-"`
+	Raw json.RawMessage `json:"-"`
+	Updated time.Time `json:"-"`
+}
+
+type Category struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type Author struct {
+	Name string `json:"name"`
+}
+
+// Keep the original JSON of every node, so it can be archived as is
+func (node *Node) UnmarshalJSON(data []byte) error {
+	type plainNode Node
+	err := json.Unmarshal(data, (*plainNode)(node))
+	if err != nil {
+		return err
+	}
+	node.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type RssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string `xml:"version,attr"`
+	Atom string `xml:"xmlns:atom,attr"`
+	Channel struct {
+	    AtomLink []AtomLink `xml:"atom:link"`
+		Title string `xml:"title"`
+	    Link string `xml:"link"`
+	    Desc string `xml:"description"`
+	    Cloud *RssCloud `xml:"cloud,omitempty"`
+	    Item []RssItem `xml:"item"`
+	} `xml:"channel"`
+	// Articles the items were built from, for feeds derived from this one
+	nodes []Node
+}
+
+type AtomLink struct {
+	Rel string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type RssItem struct {
+    Title string `xml:"title"`
+    Link string `xml:"link"`
+    Guid struct {
+    	Content string `xml:",chardata"`
+    	IsPermaLink bool `xml:"isPermaLink,attr"`
+    } `xml:"guid"`
+    Description *Cdata `xml:"description,omitempty"`
+    Category []string `xml:"category"`
+    PubDate string `xml:"pubDate"`
+    Updated string `xml:"atom:updated,omitempty"`
+    Enclosure struct {
+    	Url string `xml:"url,attr"`
+    	Length int64 `xml:"length,attr"`
+    	Type string `xml:"type,attr"`
+    } `xml:"enclosure"`
+}
+
+// Text written as a CDATA section, for HTML content
+type Cdata struct {
+	Text string `xml:",cdata"`
+}
+
+type Config struct {
+	Url string `json:"url"`
+	Source SourceConfig `json:"source"`
+	Feeds []FeedConfig `json:"feeds"`
+	Publish PublishConfig `json:"publish"`
+	Transforms []PluginConfig `json:"transforms"`
+	Pipeline []string `json:"pipeline"`
+	Filter FilterConfig `json:"filter"`
+	ThumbnailCompression string `json:"thumbnail_compression"`
+	Interval Duration `json:"interval"`
+	Schedule string `json:"schedule"`
+	Jitter int `json:"jitter"`
+	MinInterval Duration `json:"min_interval"`
+	BackoffMax Duration `json:"backoff_max"`
+	CompactXml bool `json:"compact_xml"`
+	HtmlTemplate string `json:"html_template"`
+	TitleTemplate string `json:"title_template"`
+	ItemTemplate string `json:"item_template"`
+	Links LinkConfig `json:"links"`
+	Guid GuidConfig `json:"guid"`
+	FetchQuietHours QuietHours `json:"fetch_quiet_hours"`
+	MaxAge Duration `json:"max_age"`
+	Archive string `json:"archive"`
+	MinItems int `json:"min_items"`
+	MinUpstreamItems int `json:"min_upstream_items"`
+	RedateUpdated bool `json:"redate_updated"`
+	StrictDates bool `json:"strict_dates"`
+	PublishTimeFallback string `json:"publish_time_fallback"`
+	FeedMediaType string `json:"feed_media_type"`
+	KeepDays int `json:"keep_days"`
+	KeepItems int `json:"keep_items"`
+	StateFile string `json:"state_file"`
+	Webhooks []WebhookConfig `json:"webhooks"`
+	Telegram TelegramConfig `json:"telegram"`
+	Mastodon MastodonConfig `json:"mastodon"`
+	Bluesky BlueskyConfig `json:"bluesky"`
+	Discord DiscordConfig `json:"discord"`
+	Slack SlackConfig `json:"slack"`
+	Ntfy NtfyConfig `json:"ntfy"`
+	Gotify GotifyConfig `json:"gotify"`
+	EmailDigest EmailDigestConfig `json:"email_digest"`
+	Matrix MatrixConfig `json:"matrix"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	TlsCert string `json:"tls_cert"`
+	TlsKey string `json:"tls_key"`
+	HttpRedirect string `json:"http_redirect"`
+	Acme AcmeConfig `json:"acme"`
+	Listen string `json:"listen"`
+	SocketMode string `json:"socket_mode"`
+	Auth map[string]AuthConfig `json:"auth"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	ServerLimits ServerLimitsConfig `json:"server_limits"`
+	TrustedProxies []string `json:"trusted_proxies"`
+	// Path the routes are mounted under when embedded, see NewHandler
+	BasePath string `json:"base_path"`
+	AccessLog bool `json:"access_log"`
+	CacheControl map[string]string `json:"cache_control"`
+	ReadyMaxAge Duration `json:"ready_max_age"`
+	LogLevel string `json:"log_level"`
+	LogFormat string `json:"log_format"`
+	LogOutput string `json:"log_output"`
+	Sentry SentryConfig `json:"sentry"`
+	DebugListen string `json:"debug_listen"`
+	Alerts AlertConfig `json:"alerts"`
+	WebSocket WebSocketConfig `json:"websocket"`
+	Cloud CloudConfig `json:"cloud"`
+	WebSub WebSubConfig `json:"websub"`
+	Robots RobotsConfig `json:"robots"`
+	PersonalFeeds PersonalFeedsConfig `json:"personal_feeds"`
+}
+
+// Article URL given by the source, or on the OKO.press website
+func nodeLink(node Node) string {
+	if node.Link != "" {
+		return node.Link
+	}
+	return "https://oko.press/" + node.SeoFields.Slug
+}
+
+// Publication time of article, the API returns UTC times without zone
+func nodeTime(node Node) time.Time {
+	published, _ := time.ParseInLocation("2006-01-02T15:04:05", node.Published, time.UTC)
+	return published
+}
+
+// Newest first, ties broken by ID, so the order doesn't depend on the order
+// upstream happened to return articles in
+func sortNodes(nodes []Node) {
+	sort.SliceStable(nodes, func(a, b int) bool {
+		timeA, timeB := nodeTime(nodes[a]), nodeTime(nodes[b])
+		if !timeA.Equal(timeB) {
+			return timeA.After(timeB)
+		}
+		return nodes[a].ID < nodes[b].ID
+	})
+}
+
+// Thumbnail URL passed through the configured image compression, empty
+// for articles without a picture. The compression is OKO.press's CDN, so
+// it only applies to its articles, which unlike others have no own link
+func nodeImage(node Node) string {
+	if node.Image.Url == "" || node.Link != "" {
+		return node.Image.Url
+	}
+	return config.ThumbnailCompression + node.Image.Url
+}
+
+// Identifier readers use to tell items apart
+func nodeGuid(node Node) string {
+	return node.ID
+}
+
+// Item with what every feed has, the rest is up to the item pipeline
+func newRssItem(node Node) (RssItem) {
+
+	// Change time format into RSS standard (RFC 2822)
+	okoTimeFormat := nodeTime(node)
+	rssTimeFormat := okoTimeFormat.Format("02 Jan 2006 15:04 -0700")
+
+	item := RssItem {
+		Title: node.Title,
+		Link: nodeLink(node),
+		PubDate: rssTimeFormat,
+	}
+
+	if okoTimeFormat.IsZero() {
+		slog.Debug("Unparseable publish time", "id", node.ID, "published", node.Published)
+	}
+
+	// Surface articles changed after publication
+	if !node.Updated.IsZero() {
+		item.Updated = node.Updated.UTC().Format(time.RFC3339)
+		if config.RedateUpdated {
+			item.PubDate = node.Updated.UTC().Format("02 Jan 2006 15:04 -0700")
+		}
+		slog.Debug("Marking item as updated", "id", node.ID, "updated", item.Updated, "redated", config.RedateUpdated)
+	}
+
+	// Which source the item came from in an aggregated feed
+	if node.Source != "" {
+		item.Category = append(item.Category, node.Source)
+	}
+
+	var guid = &item.Guid
+	guid.Content, guid.IsPermaLink = itemGuid(node)
+
+	return item
+}
+
+// Failed API response, with enough context to tell what upstream sent
+type UpstreamError struct {
+	StatusCode int
+	Snippet string
+	Err error
+}
+
+func (err *UpstreamError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *UpstreamError) Unwrap() error {
+	return err.Err
+}
+
+// Beginning of response body, for error reports
+func responseSnippet(body []byte) string {
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return string(body)
+}
+
+// Client for API requests, traced when tracing is enabled
+var upstreamClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// Download and decode one page of articles from the API
+func fetchNodes(ctx context.Context, url string) (nodes []Node, err error) {
+
+	ctx, span := tracer.Start(ctx, "fetch")
+	defer func() { endSpan(span, err) }()
+
+	body, err := fetchBody(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON from response into struct
+	_, decodeSpan := tracer.Start(ctx, "decode")
+	var jsonBody JsonResponse
+	err = json.Unmarshal(body, &jsonBody)
+	endSpan(decodeSpan, err)
+	if err != nil {
+		return nil, &UpstreamError{
+			StatusCode: http.StatusOK,
+			Snippet: responseSnippet(body),
+			Err: fmt.Errorf("parsing response into JSON: %w", err),
+		}
+	}
+	for _, data := range jsonBody.Data.Nodes {
+		var node Node
+		err := json.Unmarshal(data, &node)
+		if err != nil {
+			slog.Warn("Skipping article that doesn't decode, upstream schema may have changed", "error", err, "article", responseSnippet(data))
+			continue
+		}
+		// Upstream titles may carry entities and markup, cleaned up once
+		// here as nodes are decoded again after plugins and from the archive
+		node.Title = plainText(node.Title)
+		nodes = append(nodes, node)
+	}
+	span.SetAttributes(attribute.Int("oko.items", len(nodes)))
+	warnSchemaDrift(nodes)
+
+	return nodes, nil
+}
+
+// Download response body of an upstream GET request, failing on non-OK status
+func fetchBody(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
+
+	// Send GET request
+	slog.Debug("Upstream request", "url", url)
+	start := time.Now()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	httpResponse, err := upstreamClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetching URL: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	body, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	// Check server response
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, &UpstreamError{
+			StatusCode: httpResponse.StatusCode,
+			Snippet: responseSnippet(body),
+			Err: fmt.Errorf("bad HTTP status: %s, URL: %s", httpResponse.Status, httpResponse.Request.URL),
+		}
+	}
+	slog.Debug("Upstream response", "url", url, "status", httpResponse.StatusCode, "bytes", len(body), "duration", time.Since(start))
+
+	return body, nil
+}
+
+func OkoPressRss(ctx context.Context) (rss RssFeed, err error) {
+
+	ctx, span := tracer.Start(ctx, "refresh")
+	defer func() { endSpan(span, err) }()
+
+	slog.Info("Fetching articles", "feed", config.Url, "source", source.Name())
+	start := time.Now()
+	nodes, err := source.Fetch(ctx)
+	if err == nil {
+		err = checkUpstreamItems(len(nodes))
+	}
+	if err == nil {
+		nodes, err = applyTransforms(ctx, nodes)
+	}
+	if err == nil {
+		nodes = checkPublishTimes(nodes, start)
+	}
+	observeFetch(time.Since(start), err)
+	failures := recordFetchStatus(start, len(nodes), err)
+	reportFetchFailure(err, failures)
+	alertOnFailures(err, failures)
+	if archive != nil {
+		logErr := recordFetch(archive, start, len(nodes), err)
+		if logErr != nil {
+			slog.Error("Error while logging fetch", "error", logErr)
+		}
+	}
+	if err != nil {
+		return RssFeed{}, err
+	}
+
+	// Save articles into the archive. Articles from a fallback source stay
+	// out of it and the seen items, they would be announced again once the
+	// primary source is back
+	var changes []ItemChange
+	if len(nodes) == 0 || !nodes[0].Fallback {
+		var archived map[string]string
+		if archive != nil {
+			archived, err = archiveNodes(archive, nodes)
+			if err != nil {
+				slog.Error("Error while archiving articles", "error", err)
+			}
+		}
+		changes = recordDiff(nodes, archived)
+		notifyChanges(changes)
+	}
+
+	// Backfill from the archive when upstream returned too few articles
+	if archive != nil && len(nodes) < config.MinItems {
+		seen := make(map[string]bool)
+		for _, node := range nodes {
+			seen[node.ID] = true
+		}
+		archived, err := recentArchivedNodes(archive, config.MinItems - len(nodes), seen)
+		if err != nil {
+			slog.Error("Error while reading archive", "error", err)
+		}
+		slog.Info("Adding articles from archive", "feed", config.Url, "upstream", len(nodes), "archived", len(archived))
+		nodes = append(nodes, archived...)
+	}
+
+	_, generateSpan := tracer.Start(ctx, "generate")
+	rss = buildRss(nodes, itemPipeline)
+	generateSpan.SetAttributes(attribute.Int("oko.items", len(rss.Channel.Item)))
+	generateSpan.End()
+	observeRefresh(len(rss.Channel.Item), countNew(changes))
+
+	slog.Info("RSS feed generated", "feed", config.Url, "items", len(rss.Channel.Item), "duration", time.Since(start))
+	return rss, nil
+}
+
+// Create RSS feed with given articles
+func buildRss(nodes []Node, pipeline Pipeline) (RssFeed) {
+
+	// Create RSS feed and add values
+	var rss RssFeed
+	rss.Version = "2.0"
+	rss.Atom = "http://www.w3.org/2005/Atom"
+	
+	var channel = &rss.Channel
+	channel.Title = "OKO.press"
+	channel.Link = "https://oko.press"
+	channel.AtomLink = []AtomLink{{Rel: "self", Href: channel.Link}}
+	channel.Desc = "OKO.press to portal informacyjny, który publikuje najnowsze wiadomości z różnych dziedzin: polityki, gospodarki, sportu, kultury, nauki i nauki. Znajdziesz tu także wywiady, analizy, sondaże, podcasty i multimedia."
+
+	// Sorted copy, callers may rely on the order they passed
+	nodes = append([]Node(nil), nodes...)
+	sortNodes(nodes)
+
+	// Loop over nodes and add them to RSS struct
+	rssItems := make([]RssItem, 0, len(nodes))
+	for i := 0; i < len(nodes); i++ {
+		item, ok := pipeline.item(nodes[i])
+		if ok {
+			rssItems = append(rssItems, item)
+		}
+	}
+	channel.Item = rssItems
+	rss.nodes = nodes
+
+	return rss
+}
+
+// Content-Type of the feeds, always declaring UTF-8. The media type can be
+// changed with feed_media_type, e.g. to application/xml for browsers
+// which download rather than show application/rss+xml
+func feedContentType() string {
+	mediaType := config.FeedMediaType
+	if mediaType == "" {
+		mediaType = "application/rss+xml"
+	}
+	return mediaType + "; charset=utf-8"
+}
+
+// Encode RSS feed straight into the writer, without building the whole
+// document in memory first. Browsers render it with the stylesheet at the
+// given URL
+func writeRss(w io.Writer, rss RssFeed, updated time.Time, stylesheet string) (error) {
+
+	// Stylesheet for browsers and comment when last updated
+	lastUpdated := updated.Format("02 Jan 2006 15:04 -0700")
+	_, err := io.WriteString(w, xml.Header +
+		"<?xml-stylesheet type=\"text/xsl\" href=\"" + html.EscapeString(stylesheet) + "\"?>\n" +
+		"<!-- Last updated: " + lastUpdated + " -->\n")
+	if err != nil {
+		return err
+	}
+
+	// Struct to XML, indented unless compact output is configured
+	encoder := xml.NewEncoder(w)
+	if !config.CompactXml {
+		encoder.Indent("", " ")
+	}
+	err = encoder.Encode(rss)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// Scheduled and on-demand refreshes take turns
+var refreshMutex sync.Mutex
+
+// Refresh refused for now, e.g. because the previous fetch was too recent
+type DeferredError struct {
+	Reason string
+	Wait time.Duration
+}
+
+func (err *DeferredError) Error() string {
+	return fmt.Sprintf("%s, try again in %s", err.Reason, err.Wait.Round(time.Second))
+}
+
+// Successful fetch with fewer articles than min_upstream_items, likely an
+// upstream glitch rather than every article being gone
+type TooFewItemsError struct {
+	Items int
+	Min int
+}
+
+func (err *TooFewItemsError) Error() string {
+	return fmt.Sprintf("upstream returned %d articles, expected at least %d", err.Items, err.Min)
+}
+
+func checkUpstreamItems(items int) error {
+	min := config.MinUpstreamItems
+	if min < 1 {
+		min = 1
+	}
+	if items < min {
+		return &TooFewItemsError{Items: items, Min: min}
+	}
+	return nil
+}
+
+// Deferred refreshes are routine and too few articles keep the previous
+// feed, so neither is logged as an error
+func logRefreshError(err error) {
+	var deferred *DeferredError
+	var tooFew *TooFewItemsError
+	if errors.As(err, &deferred) {
+		slog.Debug("Skipping refresh", "feed", config.Url, "error", err)
+	} else if errors.As(err, &tooFew) {
+		slog.Warn("Too few articles from upstream, keeping previous feed", "feed", config.Url, "items", tooFew.Items, "min", tooFew.Min)
+	} else if err != nil {
+		slog.Error("Error while refreshing feed", "feed", config.Url, "error", err)
+	}
+}
+
+// Fetch and publish a new feed, the previous one stays on failure. Fetches
+// closer together than min_interval or during fetch quiet hours are
+// refused, whatever asked for them
+func refresh(ctx context.Context) (RssFeed, error) {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+
+	wait := time.Until(lastFetchTime().Add(minInterval()))
+	if wait > 0 {
+		return RssFeed{}, &DeferredError{Reason: "previous fetch was too recent", Wait: wait}
+	}
+
+	// Without any feed yet, there's nothing to serve overnight
+	_, updated := currentFeed()
+	wait = config.FetchQuietHours.Remaining(time.Now())
+	if wait > 0 && !updated.IsZero() {
+		return RssFeed{}, &DeferredError{Reason: "fetch quiet hours", Wait: wait}
+	}
+
+	rss, err := OkoPressRss(ctx)
+	if err == nil {
+		setFeed(rss)
+		notifyReady()
+	}
+	refreshSectionFeeds(ctx)
+	if err == nil {
+		publishFeeds(ctx)
+		go distributeWebSub()
+	}
+	return rss, err
+}
+
+func cron(ctx context.Context, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+	defer reportPanic()
+
+	// Generate feed on schedule, until shutdown. A refresh in
+	// progress is always finished, so the archive is never left half written.
+	// Failed refreshes keep the previous feed
+	for true {
+		_, err := refresh(context.WithoutCancel(ctx))
+		logRefreshError(err)
+
+		// Only refreshed on request from now on
+		if lazyOnly() {
+			<-ctx.Done()
+			return
+		}
+
+		now := time.Now()
+		next := withJitter(withBackoff(nextRefresh(now), now, consecutiveFailures()), now)
+		if earliest := lastFetchTime().Add(minInterval()); next.Before(earliest) {
+			next = earliest
+		}
+		setNextRefresh(next)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// Build version, set at build time with
+// -ldflags "-X github.com/hyperglue/oko-press-rss/okorss.version=v1.2.3"
+var version = ""
+
+// Create some global variables
+var config Config
+var port string
+
+// Latest generated feed, replaced by the refresher and read by handlers
+var feed struct {
+	sync.RWMutex
+	rss RssFeed
+	updated time.Time
+}
+
+func setFeed(rss RssFeed) {
+	feed.Lock()
+	feed.rss = rss
+	feed.updated = time.Now()
+	feed.Unlock()
+}
+
+func currentFeed() (RssFeed, time.Time) {
+	feed.RLock()
+	defer feed.RUnlock()
+	return feed.rss, feed.updated
+}
+
+// Read config file into global config
+func loadConfig(configPath string) {
+
+	// Read config file, or stdin when given as "-"
+	var data []byte
+	var err error
+	if configPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(configPath)
+	}
+	if err != nil {
+		fatal("Error while opening file", "error", err)
+	}
+
+	// Fill in secrets kept in files, then parse config into struct
+	data, err = resolveSecretFiles(data)
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		fatal("Error while parsing config file into struct", "error", err)
+	}
+
+	if logLevelFlag != "" {
+		config.LogLevel = logLevelFlag
+	}
+	err = setupLogging(config.LogLevel, config.LogFormat, config.LogOutput)
+	if err != nil {
+		fatal("Error while setting up logging", "error", err)
+	}
+}
+
+// Subcommands, run as "oko-rss <command> [options]"
+var commands = map[string]func(args []string){
+	"backfill": backfillCommand,
+	"export": exportCommand,
+	"healthcheck": healthcheckCommand,
+	"prune": pruneCommand,
+	"token": tokenCommand,
+	"validate": validateCommand,
+	"serverless": serverlessCommand,
+	"service": serviceCommand,
+}
+
+// Set up everything the feed is generated with from config
+func setupFeed() error {
+
+	err := setupSource(config)
+	if err != nil {
+		return fmt.Errorf("setting up source: %w", err)
+	}
+	itemPipeline, err = newPipeline(config.Pipeline, config.Filter)
+	if err != nil {
+		return fmt.Errorf("setting up item pipeline: %w", err)
+	}
+	err = setupSectionFeeds(config)
+	if err != nil {
+		return fmt.Errorf("setting up feeds: %w", err)
+	}
+	err = setupTransforms(config)
+	if err != nil {
+		return fmt.Errorf("setting up transforms: %w", err)
+	}
+	err = setupPublishers(config.Publish)
+	if err != nil {
+		return fmt.Errorf("setting up publishing: %w", err)
+	}
+
+	// Cron expression takes over from the fixed interval
+	err = setupSchedule(config.Schedule)
+	if err != nil {
+		return fmt.Errorf("parsing schedule: %w", err)
+	}
+	err = config.FetchQuietHours.Setup()
+	if err != nil {
+		return fmt.Errorf("parsing fetch quiet hours: %w", err)
+	}
+	err = setupTitleTemplate(config.TitleTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing title template: %w", err)
+	}
+	err = setupItemTemplate(config.ItemTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing item template: %w", err)
+	}
+	err = config.Guid.Setup()
+	if err != nil {
+		return fmt.Errorf("parsing GUID settings: %w", err)
+	}
+	return nil
+}
+
+// Run the server, or the subcommand given on the command line
+func Main() {
+
+	// Lambda runs the binary without arguments
+	if len(os.Args) == 1 && runningServerless() {
+		serverlessCommand(nil)
+		return
+	}
+
+	// Hand over to subcommand if one was given
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	// Get info from command line parameters
+	var configPath string
+	
+	usage := "Usage:\n\toko-rss [options]\tserve the feed\n\toko-rss backfill [options]\tstore older articles in the archive\n\toko-rss export [options]\tdump archived articles\n\toko-rss healthcheck [options]\tcheck a running instance is ready\n\toko-rss prune [options]\tapply the archive retention once\n\toko-rss token [options]\tprint a subscriber token for a personal feed\n\toko-rss validate [options]\tcheck the API still returns the fields used\n\toko-rss serverless [options]\tgenerate the feed on request in Lambda or Cloud Run\n\toko-rss service install|uninstall|start|stop [options]\tmanage the Windows service\n\nOptions:\n\t-p, --port\tport number (default 8000)\n\t-c, --config\tconfig file path, - for stdin\n\t--log-level\tdebug, info, warn or error (default info)\n\t--debug\tserve pprof profiling endpoints\n\t--fcgi\tserve FastCGI on the listener instead of HTTP\n"
+	flag.Usage = func() { fmt.Print(usage) }
+
+	flag.StringVar(&port, "p", "8000", "")
+	flag.StringVar(&port, "port", "8000", "")
+	flag.StringVar(&configPath, "c", "NO_CONFIG", "")
+	flag.StringVar(&configPath, "config", "NO_CONFIG", "")
+	flag.StringVar(&logLevelFlag, "log-level", "", "")
+	flag.BoolVar(&debugEnabled, "debug", false, "")
+	flag.BoolVar(&fcgiEnabled, "fcgi", false, "")
+	flag.Parse()
+
+	// Check if config file was specified
+	if configPath == "NO_CONFIG" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+
+	loadConfig(configPath)
+
+	// Open article archive if enabled
+	if config.Archive != "" {
+		var err error
+		archive, err = openArchive(config.Archive)
+		if err != nil {
+			fatal("Error while opening archive", "error", err)
+		}
+		defer archive.Close()
+	}
+
+	// Restore items seen before restart
+	if config.StateFile != "" {
+		err := loadSeenState(config.StateFile)
+		if err != nil {
+			fatal("Error while loading state file", "error", err)
+		}
+	}
+
+	err := setupFeed()
+	if err != nil {
+		fatal("Error while setting up feed", "error", err)
+	}
+
+	// Export traces when configured through OTEL_* environment variables
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		fatal("Error while setting up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Report panics and outages to Sentry when configured
+	err = setupSentry(config.Sentry)
+	if err != nil {
+		fatal("Error while setting up Sentry", "error", err)
+	}
+	defer flushSentry()
+	defer reportPanic()
+
+	startNotifiers()
+
+	// Cancelled on SIGINT or SIGTERM, e.g. from Docker or systemd
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Or by the service manager when running as a Windows service
+	ctx, stopped := serviceContext(ctx)
+	defer stopped()
+
+	// Or once a new process took over on SIGUSR2
+	ctx, shutdown := context.WithCancel(ctx)
+	defer shutdown()
+	go handleUpgrades(ctx, shutdown, configPath)
+
+	// Profiling on its own port, bound before the HTTP server reports
+	// being ready to a process it replaces
+	var wg sync.WaitGroup
+	if debugEnabled && config.DebugListen != "" {
+		listener, err := listenTcp("debug", config.DebugListen)
+		if err != nil {
+			fatal("Error while opening debug listener", "error", err)
+		}
+		wg.Add(1)
+		go serveDebug(ctx, &wg, listener)
+	}
+
+	// Run 2 concurrent functions: HTTP server and feed generator every specified seconds
+	wg.Add(2)
+	go cron(ctx, &wg)
+	go serveHttp(ctx, &wg)
+
+	// Restarted by systemd when the feed stops being refreshed
+	if watchdogInterval() > 0 {
+		wg.Add(1)
+		go watchdog(ctx, &wg)
+	}
+
+	// Keep archive within retention limits
+	if archive != nil && (config.KeepDays > 0 || config.KeepItems > 0) {
+		wg.Add(1)
+		go pruner(ctx, &wg)
+	}
+
+	// Wait for everything to wind down, archive is closed on return
+	wg.Wait()
+	slog.Info("Shut down cleanly")
+}
@@ -0,0 +1,64 @@
+package okorss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Feed served by this instance
+type FeedRoute struct {
+	Path  string
+	Title string
+}
+
+// Filled in as feed routes are registered
+var feedRoutes []FeedRoute
+
+type Opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OpmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type OpmlOutline struct {
+	Type    string `xml:"type,attr"`
+	Text    string `xml:"text,attr"`
+	Title   string `xml:"title,attr"`
+	XmlUrl  string `xml:"xmlUrl,attr"`
+	HtmlUrl string `xml:"htmlUrl,attr"`
+}
+
+func serveOpml(w http.ResponseWriter, r *http.Request) {
+
+	var opml Opml
+	opml.Version = "2.0"
+	opml.Head.Title = "OKO.press"
+	opml.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+
+	base := requestBaseUrl(r)
+	for _, feed := range feedRoutes {
+		opml.Body.Outlines = append(opml.Body.Outlines, OpmlOutline{
+			Type:    "rss",
+			Text:    feed.Title,
+			Title:   feed.Title,
+			XmlUrl:  base + feed.Path,
+			HtmlUrl: "https://oko.press",
+		})
+	}
+
+	output, err := xml.MarshalIndent(opml, "", " ")
+	if err != nil {
+		http.Error(w, "OPML unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	fmt.Fprintln(w, xml.Header+string(output))
+}
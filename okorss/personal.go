@@ -0,0 +1,137 @@
+package okorss
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Secret subscriber tokens are signed with, personalized feeds are off
+// without one
+type PersonalFeedsConfig struct {
+	Secret string `json:"secret"`
+}
+
+// Preferences carried in a subscriber token, short keys keep it short
+type PersonalFeed struct {
+	Categories []string `json:"c,omitempty"`
+	Keywords   []string `json:"k,omitempty"`
+	MaxItems   int      `json:"n,omitempty"`
+}
+
+func (personal PersonalFeed) filter(nodes []Node) []Node {
+
+	filter := WebSocketFilter{Categories: personal.Categories, Keywords: personal.Keywords}
+	var filtered []Node
+	for _, node := range nodes {
+		if personal.MaxItems > 0 && len(filtered) >= personal.MaxItems {
+			break
+		}
+		if filter.match(node) {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+func tokenSignature(payload string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Preferences and their signature, so the instance keeps no state
+func signPersonalFeed(personal PersonalFeed, secret string) (string, error) {
+	data, err := json.Marshal(personal)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + tokenSignature(payload, secret), nil
+}
+
+func parsePersonalFeed(token string, secret string) (PersonalFeed, error) {
+
+	var personal PersonalFeed
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(tokenSignature(payload, secret))) {
+		return personal, errors.New("invalid token signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return personal, err
+	}
+	err = json.Unmarshal(data, &personal)
+	return personal, err
+}
+
+// Main feed narrowed down to the preferences in the t parameter
+func servePersonalRss(w http.ResponseWriter, r *http.Request, token string) {
+
+	if config.PersonalFeeds.Secret == "" {
+		http.Error(w, "personalized feeds are not enabled", http.StatusNotFound)
+		return
+	}
+	personal, err := parsePersonalFeed(token, config.PersonalFeeds.Secret)
+	if err != nil {
+		slog.Debug("Rejected subscriber token", "client", clientIP(r), "error", err)
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+
+	refreshIfStale()
+	if feedNotReady(w) {
+		return
+	}
+	current, updated := currentFeed()
+	rss := buildRss(personal.filter(current.nodes), itemPipeline)
+	self := requestBaseUrl(r) + r.URL.RequestURI()
+	rss.Channel.AtomLink = []AtomLink{{Rel: "self", Href: self}}
+	serveFeed(w, r, &renderedPersonalFeed, self, rss, updated)
+}
+
+var renderedPersonalFeed RenderedFeed
+
+// Print a subscriber token for the given preferences
+func tokenCommand(args []string) {
+
+	var configPath, categories, keywords string
+	var maxItems int
+
+	flags := flag.NewFlagSet("token", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "NO_CONFIG", "config file path")
+	flags.StringVar(&configPath, "config", "NO_CONFIG", "config file path")
+	flags.StringVar(&categories, "category", "", "comma separated categories (names or slugs) to include")
+	flags.StringVar(&keywords, "keyword", "", "comma separated words, items must contain one in the title or lead")
+	flags.IntVar(&maxItems, "max-items", 0, "most items in the feed, all by default")
+	flags.Parse(args)
+
+	if configPath == "NO_CONFIG" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+	loadConfig(configPath)
+	if config.PersonalFeeds.Secret == "" {
+		fatal("Set personal_feeds.secret in config to sign tokens")
+	}
+
+	personal := PersonalFeed{MaxItems: maxItems}
+	if categories != "" {
+		personal.Categories = strings.Split(categories, ",")
+	}
+	if keywords != "" {
+		personal.Keywords = strings.Split(keywords, ",")
+	}
+	token, err := signPersonalFeed(personal, config.PersonalFeeds.Secret)
+	if err != nil {
+		fatal("Error while signing token", "error", err)
+	}
+	fmt.Println(mainFeedPath + "?t=" + token)
+}
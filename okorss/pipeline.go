@@ -0,0 +1,127 @@
+package okorss
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Step in turning an article into a feed item. It may change the article
+// for later steps, e.g. clean up its lead. Returning false drops the item
+type Transformer interface {
+	Transform(node *Node, item *RssItem) bool
+}
+
+type TransformerFunc func(node *Node, item *RssItem) bool
+
+func (transform TransformerFunc) Transform(node *Node, item *RssItem) bool {
+	return transform(node, item)
+}
+
+// Steps run in order for every item of a feed
+type Pipeline []Transformer
+
+// Steps by their name in "pipeline", all of them by default
+var defaultPipeline = []string{"filter", "links", "sanitize", "image", "template"}
+
+var transformerSteps = map[string]Transformer{
+	"links":    TransformerFunc(rewriteItemLink),
+	"sanitize": TransformerFunc(sanitizeItem),
+	"image":    TransformerFunc(imageEnclosure),
+	"template": TransformerFunc(templateItem),
+}
+
+// Pipeline of the main feed, also used by feeds without one of their own
+var itemPipeline Pipeline
+
+func newPipeline(steps []string, filter FilterConfig) (Pipeline, error) {
+
+	if len(steps) == 0 {
+		steps = defaultPipeline
+	}
+	var pipeline Pipeline
+	for _, step := range steps {
+		if step == "filter" {
+			pipeline = append(pipeline, filter)
+			continue
+		}
+		transformer, ok := transformerSteps[step]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline step %q", step)
+		}
+		pipeline = append(pipeline, transformer)
+	}
+	return pipeline, nil
+}
+
+// Feed item of an article, false when a step dropped it
+func (pipeline Pipeline) item(node Node) (RssItem, bool) {
+	item := newRssItem(node)
+	for _, transformer := range pipeline {
+		if !transformer.Transform(&node, &item) {
+			slog.Debug("Item dropped by pipeline", "id", node.ID)
+			return item, false
+		}
+	}
+	// Link and permalink GUID never disagree, whichever steps ran
+	if item.Guid.IsPermaLink {
+		item.Guid.Content = item.Link
+	}
+	return item, true
+}
+
+// Drops articles by category or by words in the title. Matching ignores
+// case, categories are compared by name or slug
+type FilterConfig struct {
+	IncludeCategories []string `json:"include_categories"`
+	ExcludeCategories []string `json:"exclude_categories"`
+	ExcludeTitles     []string `json:"exclude_titles"`
+}
+
+func (filter FilterConfig) Transform(node *Node, item *RssItem) bool {
+
+	if len(filter.IncludeCategories) > 0 && !inAnyCategory(*node, filter.IncludeCategories) {
+		return false
+	}
+	if inAnyCategory(*node, filter.ExcludeCategories) {
+		return false
+	}
+	title := strings.ToLower(node.Title)
+	for _, words := range filter.ExcludeTitles {
+		if strings.Contains(title, strings.ToLower(words)) {
+			return false
+		}
+	}
+	return true
+}
+
+func rewriteItemLink(node *Node, item *RssItem) bool {
+	item.Link = rewriteLink(item.Link)
+	return true
+}
+
+// Clean upstream HTML of the lead before templates see it
+func sanitizeItem(node *Node, item *RssItem) bool {
+	node.Lead = sanitizeHtml(node.Lead)
+	return true
+}
+
+func imageEnclosure(node *Node, item *RssItem) bool {
+	var enclosure = &item.Enclosure
+	enclosure.Url = nodeImage(*node)
+	enclosure.Length = 0
+	enclosure.Type = "image/jpeg"
+	if node.Image.Url == "" {
+		slog.Debug("Item has no featured image", "id", node.ID)
+	}
+	return true
+}
+
+func templateItem(node *Node, item *RssItem) bool {
+	item.Title = itemTitle(*node)
+	description := itemDescription(*node, item.Link)
+	if description != "" {
+		item.Description = &Cdata{Text: description}
+	}
+	return true
+}
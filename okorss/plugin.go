@@ -0,0 +1,138 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Version of the plugin protocol, sent with every request
+const pluginProtocolVersion = 1
+
+// External program adding a source or transform in any language. It's
+// started for every call, gets one JSON request on stdin and writes one
+// JSON response to stdout:
+//
+//	{"version": 1, "action": "fetch"}
+//	{"version": 1, "action": "transform", "items": [...]}
+//
+// Response is {"items": [...]} with items in the OKO.press API format, or
+// {"error": "..."}. Anything written to stderr ends up in the error
+type PluginConfig struct {
+	Command []string `json:"command"`
+	Timeout Duration `json:"timeout"`
+}
+
+type PluginRequest struct {
+	Version int    `json:"version"`
+	Action  string `json:"action"`
+	Items   []Node `json:"items,omitempty"`
+}
+
+type PluginResponse struct {
+	Items []Node `json:"items"`
+	Error string `json:"error"`
+}
+
+func (plugin PluginConfig) validate() error {
+	if len(plugin.Command) == 0 {
+		return fmt.Errorf("plugin command is required")
+	}
+	return nil
+}
+
+func (plugin PluginConfig) name() string {
+	return strings.Join(plugin.Command, " ")
+}
+
+// Run the plugin with the request, killing it when it takes too long
+func (plugin PluginConfig) call(ctx context.Context, request PluginRequest) ([]Node, error) {
+
+	timeout := time.Duration(plugin.Timeout)
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request.Version = pluginProtocolVersion
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, plugin.Command[0], plugin.Command[1:]...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err = command.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("running plugin %q: %w: %s", plugin.name(), err, responseSnippet(stderr.Bytes()))
+	}
+
+	var response PluginResponse
+	err = json.Unmarshal(stdout.Bytes(), &response)
+	if err != nil {
+		return nil, fmt.Errorf("parsing response of plugin %q: %w: %s", plugin.name(), err, responseSnippet(stdout.Bytes()))
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %q: %w", plugin.name(), errors.New(response.Error))
+	}
+	return response.Items, nil
+}
+
+// Articles produced by a plugin
+type PluginSource struct {
+	plugin PluginConfig
+}
+
+func newPluginSource(config Config) (Source, error) {
+	plugin := config.Source.PluginConfig
+	err := plugin.validate()
+	if err != nil {
+		return nil, err
+	}
+	return PluginSource{plugin: plugin}, nil
+}
+
+func (source PluginSource) Name() string {
+	return "plugin " + source.plugin.name()
+}
+
+func (source PluginSource) Fetch(ctx context.Context) ([]Node, error) {
+	return source.plugin.call(ctx, PluginRequest{Action: "fetch"})
+}
+
+// Plugins changing, adding or dropping fetched articles, in order
+var transforms []PluginConfig
+
+func setupTransforms(config Config) error {
+	for i, plugin := range config.Transforms {
+		err := plugin.validate()
+		if err != nil {
+			return fmt.Errorf("transform %d: %w", i+1, err)
+		}
+	}
+	transforms = config.Transforms
+	return nil
+}
+
+func applyTransforms(ctx context.Context, nodes []Node) ([]Node, error) {
+	var err error
+	for _, plugin := range transforms {
+		nodes, err = plugin.call(ctx, PluginRequest{Action: "transform", Items: nodes})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
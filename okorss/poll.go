@@ -0,0 +1,166 @@
+package okorss
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Response of /poll, cursor is passed as since in the next request
+type PollResponse struct {
+	Cursor string       `json:"cursor"`
+	Items  []NotifyItem `json:"items"`
+}
+
+// Batch of new articles, keyed by discovery time so cursors stay
+// meaningful across restarts
+type pollBatch struct {
+	cursor int64
+	nodes  []Node
+}
+
+const (
+	// Batches kept for clients catching up
+	pollHistorySize    = 100
+	pollDefaultTimeout = 30 * time.Second
+	pollMaxTimeout     = 2 * time.Minute
+)
+
+var polls struct {
+	sync.Mutex
+	batches []pollBatch
+	// Closed and replaced whenever new articles arrive
+	updated chan struct{}
+	stopped chan struct{}
+}
+
+func init() {
+	polls.updated = make(chan struct{})
+	polls.stopped = make(chan struct{})
+}
+
+// Remember new articles and wake up waiting requests
+func recordPollItems(nodes []Node) {
+
+	polls.Lock()
+	defer polls.Unlock()
+
+	cursor := time.Now().UnixNano()
+	if len(polls.batches) > 0 && cursor <= polls.batches[len(polls.batches)-1].cursor {
+		cursor = polls.batches[len(polls.batches)-1].cursor + 1
+	}
+	polls.batches = append(polls.batches, pollBatch{cursor: cursor, nodes: nodes})
+	if len(polls.batches) > pollHistorySize {
+		polls.batches = polls.batches[len(polls.batches)-pollHistorySize:]
+	}
+	close(polls.updated)
+	polls.updated = make(chan struct{})
+}
+
+// Release waiting requests on shutdown, they would hold it up otherwise
+func stopPolls() {
+	polls.Lock()
+	defer polls.Unlock()
+	select {
+	case <-polls.stopped:
+	default:
+		close(polls.stopped)
+	}
+}
+
+// Articles discovered after the cursor matching the filter, with the
+// cursor to continue from
+func pollItems(since int64, filter WebSocketFilter) (PollResponse, chan struct{}) {
+
+	polls.Lock()
+	defer polls.Unlock()
+
+	// Without a cursor start from now
+	if since == 0 {
+		cursor := time.Now().UnixNano()
+		if len(polls.batches) > 0 {
+			cursor = max(cursor, polls.batches[len(polls.batches)-1].cursor)
+		}
+		return PollResponse{Cursor: strconv.FormatInt(cursor, 10), Items: []NotifyItem{}}, polls.updated
+	}
+
+	response := PollResponse{Cursor: strconv.FormatInt(since, 10), Items: []NotifyItem{}}
+	if len(polls.batches) > 0 {
+		latest := polls.batches[len(polls.batches)-1].cursor
+		if latest > since {
+			response.Cursor = strconv.FormatInt(latest, 10)
+		}
+		for _, batch := range polls.batches {
+			if batch.cursor <= since {
+				continue
+			}
+			for _, node := range batch.nodes {
+				if filter.match(node) {
+					response.Items = append(response.Items, newNotifyItem(node))
+				}
+			}
+		}
+	}
+	return response, polls.updated
+}
+
+// Hold the request until articles newer than since appear or the timeout,
+// in seconds, passes. Takes the same category and q filters as /ws
+func servePoll(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	var since int64
+	if value := query.Get("since"); value != "" {
+		var err error
+		since, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "invalid since cursor", http.StatusBadRequest)
+			return
+		}
+	}
+	timeout := pollDefaultTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(seconds)*time.Second, pollMaxTimeout)
+	}
+	filter := WebSocketFilter{Categories: query["category"], Keywords: query["q"]}
+
+	// Waiting may take longer than the server's write timeout allows
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + config.ServerLimits.writeTimeout()))
+	if err != nil {
+		slog.Debug("Can't extend write deadline for long poll", "error", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	response, updated := pollItems(since, filter)
+	for since > 0 && len(response.Items) == 0 {
+		select {
+		case <-updated:
+		case <-timer.C:
+		case <-polls.stopped:
+		case <-r.Context().Done():
+			return
+		}
+		previous := updated
+		response, updated = pollItems(since, filter)
+		if previous == updated {
+			// Timed out or shutting down
+			break
+		}
+		// Nothing matched the filter, keep waiting from the new cursor
+		since, _ = strconv.ParseInt(response.Cursor, 10, 64)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,93 @@
+package okorss
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Networks of reverse proxies whose forwarding headers are believed
+var trustedProxies []*net.IPNet
+
+// Parse trusted_proxies from config, entries are CIDRs or single addresses
+func setupTrustedProxies() error {
+
+	trustedProxies = nil
+	for _, entry := range config.TrustedProxies {
+		if !strings.Contains(entry, "/") {
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return err
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func fromTrustedProxy(r *http.Request) bool {
+	if fromUnixSocket(r) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && isTrustedProxy(ip)
+}
+
+// Unix domain socket peers carry no address. Only local processes can
+// connect, in practice the reverse proxy in front, so they're trusted
+func fromUnixSocket(r *http.Request) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && local.Network() == "unix"
+}
+
+// Proxies in a chain append to forwarding headers, the first entry is
+// the one facing the client
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// Address of the client. When the connection comes from a trusted proxy,
+// X-Forwarded-For is walked from the right, skipping further trusted
+// proxies, as anything left of them could be forged by the client
+func clientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !fromTrustedProxy(r) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		candidate := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if candidate == nil {
+			break
+		}
+		if !isTrustedProxy(candidate) {
+			return candidate.String()
+		}
+		host = candidate.String()
+	}
+	return host
+}
@@ -0,0 +1,80 @@
+package okorss
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// How often the server applies the retention policy
+const pruneInterval = time.Hour
+
+func pruneWithConfig() {
+	pruned, err := pruneArchive(archive, config.KeepDays, config.KeepItems)
+	if err != nil {
+		slog.Error("Error while pruning archive", "error", err)
+		return
+	}
+	if pruned > 0 {
+		slog.Info("Pruned archive", "articles", pruned)
+	}
+}
+
+func pruner(ctx context.Context, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+	defer reportPanic()
+
+	for true {
+		pruneWithConfig()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pruneInterval):
+		}
+	}
+}
+
+// Apply the retention policy once and reclaim disk space
+func pruneCommand(args []string) {
+
+	var configPath string
+	var keepDays, keepItems int
+
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "NO_CONFIG", "config file path")
+	flags.StringVar(&configPath, "config", "NO_CONFIG", "config file path")
+	flags.IntVar(&keepDays, "keep-days", 0, "override keep_days from config")
+	flags.IntVar(&keepItems, "keep-items", 0, "override keep_items from config")
+	flags.Parse(args)
+
+	if configPath == "NO_CONFIG" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+	loadConfig(configPath)
+	if keepDays > 0 {
+		config.KeepDays = keepDays
+	}
+	if keepItems > 0 {
+		config.KeepItems = keepItems
+	}
+	if !openConfiguredArchive() {
+		return
+	}
+	defer archive.Close()
+
+	pruned, err := pruneArchive(archive, config.KeepDays, config.KeepItems)
+	if err != nil {
+		fatal("Error while pruning archive", "error", err)
+	}
+
+	_, err = archive.Exec("VACUUM")
+	if err != nil {
+		fatal("Error while compacting archive", "error", err)
+	}
+	slog.Info("Pruned archive", "articles", pruned)
+}
@@ -0,0 +1,139 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Feed file uploaded for static hosting
+type PublishedFile struct {
+	Name         string
+	ContentType  string
+	CacheControl string
+	Body         []byte
+}
+
+// Destination the feed files are copied to after every refresh
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, files []PublishedFile) error
+}
+
+// Static hosting of the feeds. Base URL is where the files end up being
+// served from, used for the feeds' self links
+type PublishConfig struct {
+	BaseUrl string           `json:"base_url"`
+	S3      S3Config         `json:"s3"`
+	Git     GitPublishConfig `json:"git"`
+}
+
+var publishers []Publisher
+
+func setupPublishers(config PublishConfig) error {
+	if config.S3.Bucket != "" {
+		publisher, err := newS3Publisher(config.S3)
+		if err != nil {
+			return fmt.Errorf("s3: %w", err)
+		}
+		publishers = append(publishers, publisher)
+	}
+	if config.Git.Repository != "" {
+		publisher, err := newGitPublisher(config.Git)
+		if err != nil {
+			return fmt.Errorf("git: %w", err)
+		}
+		publishers = append(publishers, publisher)
+	}
+	return nil
+}
+
+// Cache-Control of a published file, as configured for its route, or
+// until the next refresh
+func publishedCacheControl(route string) string {
+	value := cacheControlFor(route)
+	if value == "" {
+		value = fmt.Sprintf("public, max-age=%d", int(feedCacheAge().Seconds()))
+	}
+	return value
+}
+
+func renderFeedFile(name string, route string, rss RssFeed, updated time.Time) (PublishedFile, error) {
+
+	baseUrl := strings.TrimSuffix(config.Publish.BaseUrl, "/")
+	rss.Channel.AtomLink = []AtomLink{{Rel: "self", Href: baseUrl + "/" + name}}
+
+	// Published next to the feeds, in the top directory
+	stylesheet := strings.Repeat("../", strings.Count(name, "/")) + feedStylesheetPath
+	var body bytes.Buffer
+	err := writeRss(&body, rss, updated, stylesheet)
+	if err != nil {
+		return PublishedFile{}, err
+	}
+	return PublishedFile{
+		Name:         name,
+		ContentType:  feedContentType(),
+		CacheControl: publishedCacheControl(route),
+		Body:         body.Bytes(),
+	}, nil
+}
+
+// Main feed as rss.xml, additional feeds under their paths, and the
+// stylesheet they refer to
+func feedFiles() ([]PublishedFile, error) {
+
+	rss, updated := currentFeed()
+	file, err := renderFeedFile(strings.TrimPrefix(mainFeedPath, "/"), mainFeedPath, rss, updated)
+	if err != nil {
+		return nil, err
+	}
+	files := []PublishedFile{file}
+
+	for _, section := range sectionFeeds {
+		section.RLock()
+		rss, updated := section.rss, section.updated
+		section.RUnlock()
+		if updated.IsZero() {
+			continue
+		}
+		file, err := renderFeedFile(strings.TrimPrefix(section.config.Path, "/"), section.config.Path, rss, updated)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	files = append(files, PublishedFile{
+		Name:         feedStylesheetPath,
+		ContentType:  "text/xsl",
+		CacheControl: publishedCacheControl("/" + feedStylesheetPath),
+		Body:         []byte(feedStylesheet),
+	})
+	return files, nil
+}
+
+// Upload the current feeds everywhere configured. A failed upload is
+// retried with the next refresh
+func publishFeeds(ctx context.Context) {
+
+	if len(publishers) == 0 {
+		return
+	}
+	files, err := feedFiles()
+	if err != nil {
+		slog.Error("Error while rendering feeds for publishing", "error", err)
+		return
+	}
+	for _, publisher := range publishers {
+		start := time.Now()
+		err := publisher.Publish(ctx, files)
+		if err != nil {
+			slog.Error("Error while publishing feeds", "publisher", publisher.Name(), "error", err)
+			continue
+		}
+		slog.Info("Published feeds", "publisher", publisher.Name(), "files", len(files), "duration", time.Since(start))
+	}
+}
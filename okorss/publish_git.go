@@ -0,0 +1,161 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repository branch the feed files are committed to, e.g. gh-pages for
+// GitHub Pages. Credentials go in the URL or the usual git configuration
+type GitPublishConfig struct {
+	Repository  string `json:"repository"`
+	Branch      string `json:"branch"`
+	Directory   string `json:"directory"`
+	Path        string `json:"path"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+	Message     string `json:"message"`
+}
+
+// Keeps a clone of the branch and pushes a commit whenever the feeds
+// changed. Only the timestamp changing doesn't count
+type GitPublisher struct {
+	config GitPublishConfig
+}
+
+func newGitPublisher(config GitPublishConfig) (*GitPublisher, error) {
+
+	_, err := exec.LookPath("git")
+	if err != nil {
+		return nil, err
+	}
+	if config.Branch == "" {
+		config.Branch = "gh-pages"
+	}
+	if config.Directory == "" {
+		config.Directory = "publish-repo"
+	}
+	if config.AuthorName == "" {
+		config.AuthorName = "oko-press-rss"
+	}
+	if config.AuthorEmail == "" {
+		config.AuthorEmail = "oko-press-rss@localhost"
+	}
+	if config.Message == "" {
+		config.Message = "Update feeds"
+	}
+	return &GitPublisher{config: config}, nil
+}
+
+// Repository without credentials, for logs
+func (publisher *GitPublisher) Name() string {
+	parsed, err := url.Parse(publisher.config.Repository)
+	if err != nil || parsed.Host == "" {
+		return "git " + publisher.config.Repository
+	}
+	return "git " + parsed.Redacted()
+}
+
+func (publisher *GitPublisher) git(ctx context.Context, args ...string) (string, error) {
+
+	var output bytes.Buffer
+	command := exec.CommandContext(ctx, "git", args...)
+	command.Dir = publisher.config.Directory
+	command.Stdout = &output
+	command.Stderr = &output
+	command.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	err := command.Run()
+	if err != nil {
+		return output.String(), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(output.String()))
+	}
+	return output.String(), nil
+}
+
+// Clone on first use, then follow the remote branch. A branch not on the
+// remote yet is started without history
+func (publisher *GitPublisher) sync(ctx context.Context) error {
+
+	config := publisher.config
+	_, err := os.Stat(filepath.Join(config.Directory, ".git"))
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.MkdirAll(config.Directory, 0755)
+		if err != nil {
+			return err
+		}
+		_, err = publisher.git(ctx, "init", "--quiet")
+		if err != nil {
+			return err
+		}
+		_, err = publisher.git(ctx, "remote", "add", "origin", config.Repository)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	output, err := publisher.git(ctx, "ls-remote", "--heads", "origin", config.Branch)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(output) == "" {
+		_, err = publisher.git(ctx, "checkout", "--quiet", "--orphan", config.Branch)
+		return err
+	}
+
+	_, err = publisher.git(ctx, "fetch", "--quiet", "--depth", "1", "origin", config.Branch)
+	if err != nil {
+		return err
+	}
+	_, err = publisher.git(ctx, "checkout", "--quiet", "-B", config.Branch, "FETCH_HEAD")
+	if err != nil {
+		return err
+	}
+	_, err = publisher.git(ctx, "reset", "--quiet", "--hard", "FETCH_HEAD")
+	return err
+}
+
+func (publisher *GitPublisher) Publish(ctx context.Context, files []PublishedFile) error {
+
+	config := publisher.config
+	err := publisher.sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := filepath.Join(config.Directory, config.Path, file.Name)
+		err = os.MkdirAll(filepath.Dir(name), 0755)
+		if err == nil {
+			err = os.WriteFile(name, file.Body, 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = publisher.git(ctx, "add", "--all")
+	if err != nil {
+		return err
+	}
+	// Exit status 1 means there are changes besides the timestamp comment
+	_, err = publisher.git(ctx, "diff", "--cached", "--quiet", "-I", "<!-- Last updated: .* -->")
+	if err == nil {
+		return nil
+	}
+
+	_, err = publisher.git(ctx, "-c", "user.name="+config.AuthorName, "-c", "user.email="+config.AuthorEmail,
+		"commit", "--quiet", "--message", config.Message)
+	if err != nil {
+		return err
+	}
+	_, err = publisher.git(ctx, "push", "--quiet", "origin", "HEAD:refs/heads/"+config.Branch)
+	return err
+}
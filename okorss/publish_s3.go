@@ -0,0 +1,66 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Bucket on AWS S3 or a compatible service such as MinIO, R2 or B2.
+// Without keys in config the usual AWS_* environment variables are used
+type S3Config struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	Insecure  bool   `json:"insecure"`
+}
+
+type S3Publisher struct {
+	config S3Config
+	client *minio.Client
+}
+
+func newS3Publisher(config S3Config) (*S3Publisher, error) {
+
+	if config.Endpoint == "" {
+		config.Endpoint = "s3.amazonaws.com"
+	}
+	creds := credentials.NewEnvAWS()
+	if config.AccessKey != "" {
+		creds = credentials.NewStaticV4(config.AccessKey, config.SecretKey, "")
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !config.Insecure,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Publisher{config: config, client: client}, nil
+}
+
+func (publisher *S3Publisher) Name() string {
+	return "s3://" + publisher.config.Bucket + "/" + publisher.config.Prefix
+}
+
+func (publisher *S3Publisher) Publish(ctx context.Context, files []PublishedFile) error {
+	for _, file := range files {
+		key := path.Join(publisher.config.Prefix, file.Name)
+		_, err := publisher.client.PutObject(ctx, publisher.config.Bucket, key, bytes.NewReader(file.Body), int64(len(file.Body)), minio.PutObjectOptions{
+			ContentType:  file.ContentType,
+			CacheControl: file.CacheControl,
+		})
+		if err != nil {
+			return fmt.Errorf("uploading %s: %w", key, err)
+		}
+	}
+	return nil
+}
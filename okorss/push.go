@@ -0,0 +1,109 @@
+package okorss
+
+import (
+	"strings"
+	"time"
+)
+
+type NtfyConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Server   string `json:"server"`
+	Topic    string `json:"topic"`
+	Token    string `json:"token"`
+	Priority int    `json:"priority"`
+}
+
+type GotifyConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Url      string `json:"url"`
+	Token    string `json:"token"`
+	Priority int    `json:"priority"`
+}
+
+type NtfyNotifier struct {
+	config NtfyConfig
+}
+
+type GotifyNotifier struct {
+	config GotifyConfig
+}
+
+func (ntfy NtfyNotifier) Name() string {
+	return "ntfy " + ntfy.config.Topic
+}
+
+// Publish one message per item, tapping it opens the article
+func (ntfy NtfyNotifier) Notify(nodes []Node) error {
+
+	server := strings.TrimSuffix(ntfy.config.Server, "/")
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	headers := make(map[string]string)
+	if ntfy.config.Token != "" {
+		headers["Authorization"] = "Bearer " + ntfy.config.Token
+	}
+
+	for _, node := range nodes {
+		item := newNotifyItem(node)
+		message := map[string]interface{}{
+			"topic":   ntfy.config.Topic,
+			"title":   item.Title,
+			"message": item.Lead,
+			"click":   item.Link,
+		}
+		if item.Lead == "" {
+			message["message"] = item.Link
+		}
+		if ntfy.config.Priority > 0 {
+			message["priority"] = ntfy.config.Priority
+		}
+		if item.Image != "" {
+			message["attach"] = item.Image
+		}
+
+		err := retry(3, 5*time.Second, func() error {
+			return sendJson("POST", server, headers, message, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gotify GotifyNotifier) Name() string {
+	return "Gotify " + gotify.config.Url
+}
+
+func (gotify GotifyNotifier) Notify(nodes []Node) error {
+
+	// The token goes in a header, errors carry the URL into logs
+	endpoint := strings.TrimSuffix(gotify.config.Url, "/") + "/message"
+	headers := map[string]string{"X-Gotify-Key": gotify.config.Token}
+
+	for _, node := range nodes {
+		item := newNotifyItem(node)
+		message := map[string]interface{}{
+			"title":    item.Title,
+			"message":  item.Link,
+			"priority": gotify.config.Priority,
+			"extras": map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]string{"url": item.Link},
+				},
+			},
+		}
+		if item.Lead != "" {
+			message["message"] = item.Lead + "\n\n" + item.Link
+		}
+
+		err := retry(3, 5*time.Second, func() error {
+			return sendJson("POST", endpoint, headers, message, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package okorss
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrDefaultSize = 256
+	qrMaxSize     = 1024
+)
+
+// QR code of a feed's address, for sharing it in print. The main feed by
+// default, another one by its path in feed, size in pixels
+func serveQr(w http.ResponseWriter, r *http.Request) {
+
+	path := mainFeedPath
+	if feed := r.URL.Query().Get("feed"); feed != "" {
+		path = feed
+	}
+	if !isFeedRoute(path) {
+		http.Error(w, "no feed at "+path, http.StatusNotFound)
+		return
+	}
+	size := qrDefaultSize
+	if value := r.URL.Query().Get("size"); value != "" {
+		var err error
+		size, err = strconv.Atoi(value)
+		if err != nil || size < 64 || size > qrMaxSize {
+			http.Error(w, "size must be between 64 and "+strconv.Itoa(qrMaxSize), http.StatusBadRequest)
+			return
+		}
+	}
+
+	png, err := qrcode.Encode(requestBaseUrl(r)+path, qrcode.Medium, size)
+	if err != nil {
+		http.Error(w, "QR code unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
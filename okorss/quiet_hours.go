@@ -0,0 +1,78 @@
+package okorss
+
+import (
+	"fmt"
+	"time"
+)
+
+// Daily window given as "HH:MM" times in a timezone, e.g. 23:00 to 06:00.
+// The window may wrap around midnight, equal start and end disable it
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+
+	start, end time.Duration
+	location   *time.Location
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// Validate settings, must be called before use
+func (quiet *QuietHours) Setup() error {
+
+	if quiet.Start == "" && quiet.End == "" {
+		return nil
+	}
+
+	var err error
+	quiet.start, err = parseClock(quiet.Start)
+	if err != nil {
+		return err
+	}
+	quiet.end, err = parseClock(quiet.End)
+	if err != nil {
+		return err
+	}
+
+	quiet.location = time.Local
+	if quiet.Timezone != "" {
+		quiet.location, err = time.LoadLocation(quiet.Timezone)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// How long until the quiet window ends, zero when outside of it
+func (quiet *QuietHours) Remaining(now time.Time) time.Duration {
+
+	if quiet.location == nil || quiet.start == quiet.end {
+		return 0
+	}
+
+	local := now.In(quiet.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, quiet.location)
+	clock := local.Sub(midnight)
+
+	switch {
+	case quiet.start < quiet.end && clock >= quiet.start && clock < quiet.end:
+		return quiet.end - clock
+	case quiet.start > quiet.end && clock >= quiet.start:
+		return 24*time.Hour - clock + quiet.end
+	case quiet.start > quiet.end && clock < quiet.end:
+		return quiet.end - clock
+	}
+	return 0
+}
+
+func (quiet *QuietHours) Active(now time.Time) bool {
+	return quiet.Remaining(now) > 0
+}
@@ -0,0 +1,63 @@
+package okorss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursSetup(t *testing.T) {
+
+	tests := []struct {
+		quiet   QuietHours
+		wantErr bool
+	}{
+		{QuietHours{}, false},
+		{QuietHours{Start: "23:00", End: "06:00"}, false},
+		{QuietHours{Start: "23:00", End: "06:00", Timezone: "Europe/Warsaw"}, false},
+		{QuietHours{Start: "23:00"}, true},
+		{QuietHours{Start: "25:00", End: "06:00"}, true},
+		{QuietHours{Start: "11pm", End: "06:00"}, true},
+		{QuietHours{Start: "23:00", End: "06:00", Timezone: "Mars/Olympus"}, true},
+	}
+
+	for _, test := range tests {
+		err := test.quiet.Setup()
+		if (err != nil) != test.wantErr {
+			t.Errorf("Setup(%+v) error = %v, want error %v", test.quiet, err, test.wantErr)
+		}
+	}
+}
+
+func TestQuietHoursRemaining(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		clock string
+		want  time.Duration
+	}{
+		{"before window", "09:00", "17:00", "08:30", 0},
+		{"inside window", "09:00", "17:00", "16:00", time.Hour},
+		{"at end", "09:00", "17:00", "17:00", 0},
+		{"wrapping, evening", "23:00", "06:00", "23:30", 6*time.Hour + 30*time.Minute},
+		{"wrapping, morning", "23:00", "06:00", "05:00", time.Hour},
+		{"wrapping, outside", "23:00", "06:00", "12:00", 0},
+		{"disabled", "10:00", "10:00", "10:00", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quiet := QuietHours{Start: test.start, End: test.end, Timezone: "UTC"}
+			err := quiet.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock, _ := time.Parse("15:04", test.clock)
+			now := time.Date(2025, 5, 1, clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+			if got := quiet.Remaining(now); got != test.want {
+				t.Errorf("Remaining() at %s = %s, want %s", test.clock, got, test.want)
+			}
+		})
+	}
+}
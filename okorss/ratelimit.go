@@ -0,0 +1,97 @@
+package okorss
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Buckets unused for this long are forgotten
+const rateLimitIdle = 10 * time.Minute
+
+type RateLimitConfig struct {
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	Burst             int     `json:"burst"`
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Token bucket per client IP. Every client may do a burst of requests,
+// after that tokens refill at the configured rate
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	burst := config.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    config.RequestsPerMinute / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+	}
+}
+
+// Take a token for client, or return how long until one is available
+func (limiter *rateLimiter) take(client string, now time.Time) (bool, time.Duration) {
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	// Drop idle buckets now and then, so memory doesn't grow with every IP
+	if now.Sub(limiter.swept) > rateLimitIdle {
+		for key, bucket := range limiter.buckets {
+			if now.Sub(bucket.updated) > rateLimitIdle {
+				delete(limiter.buckets, key)
+			}
+		}
+		limiter.swept = now
+	}
+
+	bucket, ok := limiter.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: limiter.burst, updated: now}
+		limiter.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(limiter.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*limiter.rate)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / limiter.rate * float64(time.Second))
+	return false, wait
+}
+
+func rateLimit(config RateLimitConfig, next http.Handler) http.Handler {
+
+	if config.RequestsPerMinute <= 0 {
+		return next
+	}
+	limiter := newRateLimiter(config)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := limiter.take(clientIP(r), time.Now())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
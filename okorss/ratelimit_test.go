@@ -0,0 +1,71 @@
+package okorss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	type take struct {
+		client  string
+		after   time.Duration
+		allowed bool
+		wait    time.Duration
+	}
+	tests := []struct {
+		name   string
+		config RateLimitConfig
+		takes  []take
+	}{
+		{"burst then wait", RateLimitConfig{RequestsPerMinute: 60, Burst: 2}, []take{
+			{"a", 0, true, 0},
+			{"a", 0, true, 0},
+			{"a", 0, false, time.Second},
+			{"a", 500 * time.Millisecond, false, 500 * time.Millisecond},
+			{"a", time.Second, true, 0},
+		}},
+		{"burst of at least one", RateLimitConfig{RequestsPerMinute: 6}, []take{
+			{"a", 0, true, 0},
+			{"a", 0, false, 10 * time.Second},
+		}},
+		{"clients are separate", RateLimitConfig{RequestsPerMinute: 60, Burst: 1}, []take{
+			{"a", 0, true, 0},
+			{"a", 0, false, time.Second},
+			{"b", 0, true, 0},
+		}},
+		{"refill stops at burst", RateLimitConfig{RequestsPerMinute: 60, Burst: 2}, []take{
+			{"a", 0, true, 0},
+			{"a", time.Hour, true, 0},
+			{"a", time.Hour, true, 0},
+			{"a", time.Hour, false, time.Second},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := newRateLimiter(test.config)
+			for i, take := range test.takes {
+				allowed, wait := limiter.take(take.client, start.Add(take.after))
+				if allowed != take.allowed || wait != take.wait {
+					t.Errorf("take %d = %v, %s, want %v, %s", i, allowed, wait, take.allowed, take.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterForgetsIdle(t *testing.T) {
+
+	start := time.Now()
+	limiter := newRateLimiter(RateLimitConfig{RequestsPerMinute: 60, Burst: 1})
+	limiter.take("a", start)
+	limiter.take("b", start.Add(rateLimitIdle+time.Minute))
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := limiter.buckets["b"]; !ok {
+		t.Error("active bucket was dropped")
+	}
+}
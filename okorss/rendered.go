@@ -0,0 +1,197 @@
+package okorss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Bounds the cache, as the base URL comes from the client controlled Host
+// header. The least recently used variant makes room for a new one
+const maxRenderedVariants = 16
+
+// Moderate level, variants for new hosts are compressed on request
+const brotliLevel = 5
+
+// Scratch buffers for rendering, reused so refreshes don't grow new ones
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// Very large buffers are left to the collector rather than kept around
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= 4<<20 {
+		bufferPool.Put(buffer)
+	}
+}
+
+// Compressors are large, they're reset for every variant instead
+var gzipWriters = sync.Pool{New: func() interface{} {
+	writer, _ := gzip.NewWriterLevel(nil, gzip.BestCompression)
+	return writer
+}}
+
+var brotliWriters = sync.Pool{New: func() interface{} {
+	return brotli.NewWriterLevel(nil, brotliLevel)
+}}
+
+// Serialized feed, rendered once per refresh so serving it is a plain copy.
+// Variants are keyed by what requests change in it, like the base URL of
+// self links
+type RenderedFeed struct {
+	sync.Mutex
+	updated  time.Time
+	variants map[string]*list.Element
+	// Keys by use, most recent in front
+	recent list.List
+}
+
+// Bytes of one variant, also compressed up front so busy instances don't
+// compress the same feed for every request
+type RenderedVariant struct {
+	key    string
+	etag   string
+	plain  []byte
+	gzip   []byte
+	brotli []byte
+}
+
+// Variant for the feed generated at updated, rendering it on first request
+// after a refresh. Rendering and compression happen outside the lock, so
+// readers of cached variants never wait for them
+func (rendered *RenderedFeed) get(key string, updated time.Time, render func(w io.Writer) error) (*RenderedVariant, error) {
+
+	rendered.Lock()
+	if !rendered.updated.Equal(updated) || rendered.variants == nil {
+		rendered.updated = updated
+		rendered.variants = make(map[string]*list.Element)
+		rendered.recent.Init()
+	}
+	if element, ok := rendered.variants[key]; ok {
+		rendered.recent.MoveToFront(element)
+		rendered.Unlock()
+		return element.Value.(*RenderedVariant), nil
+	}
+	rendered.Unlock()
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	err := render(buffer)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := compressVariant(buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	variant.key = key
+
+	// Concurrent requests may have rendered it too, or a refresh replaced
+	// the feed meanwhile
+	rendered.Lock()
+	defer rendered.Unlock()
+	if !rendered.updated.Equal(updated) {
+		return variant, nil
+	}
+	if element, ok := rendered.variants[key]; ok {
+		rendered.recent.MoveToFront(element)
+		return element.Value.(*RenderedVariant), nil
+	}
+	if len(rendered.variants) >= maxRenderedVariants {
+		oldest := rendered.recent.Back()
+		rendered.recent.Remove(oldest)
+		delete(rendered.variants, oldest.Value.(*RenderedVariant).key)
+	}
+	rendered.variants[key] = rendered.recent.PushFront(variant)
+	return variant, nil
+}
+
+// Copies of the rendered bytes sized exactly, the scratch buffers go back
+// to their pool
+func compressVariant(plain []byte) (*RenderedVariant, error) {
+
+	hash := sha256.Sum256(plain)
+	variant := &RenderedVariant{plain: bytes.Clone(plain), etag: hex.EncodeToString(hash[:8])}
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	gzipWriter := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(gzipWriter)
+	gzipWriter.Reset(buffer)
+	gzipWriter.Write(plain)
+	err := gzipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	variant.gzip = bytes.Clone(buffer.Bytes())
+
+	buffer.Reset()
+	brotliWriter := brotliWriters.Get().(*brotli.Writer)
+	defer brotliWriters.Put(brotliWriter)
+	brotliWriter.Reset(buffer)
+	brotliWriter.Write(plain)
+	err = brotliWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	variant.brotli = bytes.Clone(buffer.Bytes())
+	return variant, nil
+}
+
+// Whether the client accepts the content coding, per Accept-Encoding
+func acceptsEncoding(r *http.Request, coding string) bool {
+
+	accepted := false
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			quality, _ = strconv.ParseFloat(value, 64)
+		}
+		// An explicit coding overrides the wildcard
+		if name == coding {
+			return quality > 0
+		}
+		accepted = quality > 0
+	}
+	return accepted
+}
+
+// Write the smallest encoding the client accepts, brotli before gzip.
+// ServeContent answers conditional and range requests against the ETag,
+// which differs per encoding, and modtime, the time of the refresh
+func (variant *RenderedVariant) write(w http.ResponseWriter, r *http.Request, modtime time.Time) {
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	data := variant.plain
+	etag := variant.etag
+	if acceptsEncoding(r, "br") {
+		w.Header().Set("Content-Encoding", "br")
+		data = variant.brotli
+		etag += "-br"
+	} else if acceptsEncoding(r, "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		data = variant.gzip
+		etag += "-gzip"
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(data))
+}
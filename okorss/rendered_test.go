@@ -0,0 +1,36 @@
+package okorss
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+
+	tests := []struct {
+		header string
+		coding string
+		want   bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"gzip, deflate, br", "br", true},
+		{"GZIP", "gzip", true},
+		{"deflate", "gzip", false},
+		{"gzip;q=0", "gzip", false},
+		{"gzip; q=0.5", "gzip", true},
+		{"*", "br", true},
+		{"*;q=0", "br", false},
+		{"*, br;q=0", "br", false},
+		{"br;q=0, *", "br", false},
+		{"*;q=0, gzip", "gzip", true},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/rss.xml", nil)
+		r.Header.Set("Accept-Encoding", test.header)
+		if got := acceptsEncoding(r, test.coding); got != test.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", test.header, test.coding, got, test.want)
+		}
+	}
+}
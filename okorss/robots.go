@@ -0,0 +1,56 @@
+package okorss
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Extra rules for all crawlers, or the whole robots.txt in content
+type RobotsConfig struct {
+	Allow    []string `json:"allow"`
+	Disallow []string `json:"disallow"`
+	Content  string   `json:"content"`
+}
+
+// Routes crawlers have no business with
+var robotsDisallowed = []string{
+	"/refresh",
+	"/status.json",
+	"/stats.json",
+	"/metrics",
+	"/healthz",
+	"/readyz",
+	pprofPath,
+	webSubHubPath,
+	cloudPath,
+	"/ws",
+	"/poll",
+}
+
+// Feeds and their stylesheet are fine to crawl, internal routes are not
+func robotsTxt() string {
+
+	if config.Robots.Content != "" {
+		return config.Robots.Content
+	}
+
+	var builder strings.Builder
+	builder.WriteString("User-agent: *\n")
+	for _, feed := range feedRoutes {
+		builder.WriteString("Allow: " + feed.Path + "\n")
+	}
+	builder.WriteString("Allow: /" + feedStylesheetPath + "\n")
+	for _, path := range config.Robots.Allow {
+		builder.WriteString("Allow: " + path + "\n")
+	}
+	for _, path := range append(robotsDisallowed, config.Robots.Disallow...) {
+		builder.WriteString("Disallow: " + path + "\n")
+	}
+	return builder.String()
+}
+
+func serveRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, robotsTxt())
+}
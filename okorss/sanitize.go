@@ -0,0 +1,37 @@
+package okorss
+
+import (
+	"html"
+	"html/template"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Allow-list of formatting, links and images that feed readers can render
+// safely. Scripts, styles, iframes and on* event handlers are dropped
+var htmlPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.RequireNoFollowOnLinks(false)
+	policy.AddTargetBlankToFullyQualifiedLinks(false)
+	policy.AllowAttrs("loading").OnElements("img")
+	return policy
+}()
+
+// Clean upstream or rendered HTML before it is embedded in a feed
+func sanitizeHtml(markup string) string {
+	return htmlPolicy.Sanitize(markup)
+}
+
+// Template function marking upstream HTML as safe once it's been cleaned,
+// so it's rendered rather than escaped, e.g. "{{sanitize .Lead}}"
+func sanitizeTemplateHtml(markup string) template.HTML {
+	return template.HTML(sanitizeHtml(markup))
+}
+
+var textPolicy = bluemonday.StrictPolicy()
+
+// Upstream HTML reduced to plain text, e.g. for WordPress titles
+func plainText(markup string) string {
+	return strings.TrimSpace(html.UnescapeString(textPolicy.Sanitize(markup)))
+}
@@ -0,0 +1,84 @@
+package okorss
+
+import (
+	"math/rand"
+	"time"
+
+	cronparser "github.com/robfig/cron/v3"
+)
+
+// Refresh schedule from config, nil when refreshing at a fixed interval
+var refreshSchedule cronparser.Schedule
+
+// Parse the standard 5 field cron expression, e.g. "*/15 6-23 * * *".
+// Times are local unless prefixed with "CRON_TZ=Europe/Warsaw "
+func setupSchedule(expression string) error {
+	if expression == "" {
+		return nil
+	}
+	schedule, err := cronparser.ParseStandard(expression)
+	if err != nil {
+		return err
+	}
+	refreshSchedule = schedule
+	return nil
+}
+
+// Floor on time between upstream fetches, 60 seconds unless configured
+func minInterval() time.Duration {
+	if config.MinInterval != 0 {
+		return time.Duration(config.MinInterval)
+	}
+	return time.Minute
+}
+
+// Time of the refresh following the given one, moved past fetch quiet
+// hours if it would fall into them
+func nextRefresh(after time.Time) time.Time {
+	next := after.Add(time.Duration(config.Interval))
+	if refreshSchedule != nil {
+		next = refreshSchedule.Next(after)
+	}
+	return next.Add(config.FetchQuietHours.Remaining(next))
+}
+
+// Stretch the wait exponentially while upstream keeps failing, up to
+// backoff_max (1 hour by default) but never below the normal wait
+func withBackoff(next time.Time, now time.Time, failures int) time.Time {
+
+	if failures == 0 {
+		return next
+	}
+	limit := time.Hour
+	if config.BackoffMax > 0 {
+		limit = time.Duration(config.BackoffMax)
+	}
+
+	wait := next.Sub(now)
+	for i := 0; i < failures && wait < limit; i++ {
+		wait *= 2
+	}
+	if wait > limit {
+		wait = limit
+	}
+	if wait < next.Sub(now) {
+		return next
+	}
+	return now.Add(wait)
+}
+
+// Spread refreshes of instances started together by moving the next one
+// randomly by up to the configured percentage of the wait. Cron schedules
+// are only ever delayed, so the same slot isn't fetched twice
+func withJitter(next time.Time, now time.Time) time.Time {
+
+	if config.Jitter <= 0 {
+		return next
+	}
+	wait := next.Sub(now)
+	spread := time.Duration(float64(wait) * float64(config.Jitter) / 100 * rand.Float64())
+	if refreshSchedule == nil && rand.Intn(2) == 0 {
+		spread = -spread
+	}
+	return next.Add(spread)
+}
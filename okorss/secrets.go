@@ -0,0 +1,144 @@
+package okorss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Secrets can be kept out of the config file, e.g. in Docker or Podman
+// secrets: "password_file": "/run/secrets/smtp" stands for "password"
+// with the contents of the file. Applies to any string setting, as long
+// as the config has no "_file" setting of that name already, such as
+// state_file. Lists of strings like auth tokens take a file with one
+// entry per line, and entries of string maps like auth users work the
+// same as settings: "alice_file" gives the password of alice
+func resolveSecretFiles(data []byte) ([]byte, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveSecrets(value, reflect.TypeOf(Config{}))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Walk the config value along with the type it's decoded into
+func resolveSecrets(value interface{}, target reflect.Type) error {
+
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		if target.Kind() != reflect.Slice {
+			return nil
+		}
+		for _, item := range value {
+			err := resolveSecrets(item, target.Elem())
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if target.Kind() == reflect.Map {
+			for key, item := range value {
+				name, isFile := strings.CutSuffix(key, "_file")
+				path, isString := item.(string)
+				if isFile && isString && target.Elem().Kind() == reflect.String {
+					secret, err := readSecretFile(key, path)
+					if err != nil {
+						return err
+					}
+					value[name] = secret
+					delete(value, key)
+					continue
+				}
+				err := resolveSecrets(item, target.Elem())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if target.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := jsonFields(target)
+		for key, item := range value {
+			field, ok := fields[key]
+			if ok {
+				err := resolveSecrets(item, field)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			name := strings.TrimSuffix(key, "_file")
+			field, ok = fields[name]
+			path, isString := item.(string)
+			if name == key || !ok || !isString {
+				continue
+			}
+			isList := field.Kind() == reflect.Slice && field.Elem().Kind() == reflect.String
+			if field.Kind() != reflect.String && !isList {
+				continue
+			}
+			secret, err := readSecretFile(key, path)
+			if err != nil {
+				return err
+			}
+			delete(value, key)
+			if !isList {
+				value[name] = secret
+				continue
+			}
+			var entries []interface{}
+			for _, line := range strings.Split(secret, "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" {
+					entries = append(entries, line)
+				}
+			}
+			value[name] = entries
+		}
+	}
+	return nil
+}
+
+func readSecretFile(key string, path string) (string, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
+
+// Types of struct fields by their JSON name, including embedded structs
+func jsonFields(target reflect.Type) map[string]reflect.Type {
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			for embeddedName, embeddedType := range jsonFields(field.Type) {
+				fields[embeddedName] = embeddedType
+			}
+			continue
+		}
+		if name != "" && name != "-" {
+			fields[name] = field.Type
+		}
+	}
+	return fields
+}
@@ -0,0 +1,150 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Filters of the OKO.press API for its sections, as used by the section
+// pages of the website. Other filters can be given in "where"
+var okoPressSections = map[string]string{
+	"articles":    `{"status":{"_eq":"published"},"type":{"_nin":["micro_analysis","micro_analysis_light"]}}`,
+	"analyses":    `{"status":{"_eq":"published"},"type":{"_in":["micro_analysis","micro_analysis_light"]}}`,
+	"fact-checks": `{"status":{"_eq":"published"},"type":{"_eq":"fact_check"}}`,
+	"newsletters": `{"status":{"_eq":"published"},"type":{"_eq":"newsletter"}}`,
+}
+
+// API URL with the filter in its "variables" replaced by the section's
+func sectionUrl(apiUrl string, section string, where json.RawMessage) (string, error) {
+
+	if section != "" {
+		filter, ok := okoPressSections[section]
+		if !ok {
+			return "", fmt.Errorf("unknown OKO.press section %q", section)
+		}
+		where = json.RawMessage(filter)
+	}
+	if where == nil {
+		return apiUrl, nil
+	}
+
+	parsed, err := url.Parse(apiUrl)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	variables := make(map[string]json.RawMessage)
+	err = json.Unmarshal([]byte(query.Get("variables")), &variables)
+	if err != nil {
+		return "", fmt.Errorf("parsing variables of the url: %w", err)
+	}
+	variables["where"] = where
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	query.Set("variables", string(encoded))
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// Additional feed served from its own source, e.g. an OKO.press section
+type FeedConfig struct {
+	Path     string       `json:"path"`
+	Title    string       `json:"title"`
+	Source   SourceConfig `json:"source"`
+	Pipeline []string     `json:"pipeline"`
+	Filter   FilterConfig `json:"filter"`
+}
+
+// Additional feed with the latest result of its source. These are fetched
+// along with the main feed, but aren't archived nor notified about
+type SectionFeed struct {
+	config   FeedConfig
+	source   Source
+	pipeline Pipeline
+
+	sync.RWMutex
+	rss      RssFeed
+	updated  time.Time
+	rendered RenderedFeed
+}
+
+var sectionFeeds []*SectionFeed
+
+func setupSectionFeeds(config Config) error {
+
+	for _, feedConfig := range config.Feeds {
+		if feedConfig.Path == "" || feedConfig.Path == "/" || feedConfig.Path == mainFeedPath {
+			return fmt.Errorf("feed %q needs a path other than / and %s", feedConfig.Title, mainFeedPath)
+		}
+		sourceConfig := config
+		sourceConfig.Source = feedConfig.Source
+		source, err := newSource(sourceConfig)
+		if err != nil {
+			return fmt.Errorf("feed %s: %w", feedConfig.Path, err)
+		}
+		if feedConfig.Title == "" {
+			feedConfig.Title = "OKO.press (" + feedConfig.Path + ")"
+		}
+
+		// Main feed's pipeline unless the feed has steps or a filter of its own
+		pipeline := itemPipeline
+		if len(feedConfig.Pipeline) > 0 || !reflect.DeepEqual(feedConfig.Filter, FilterConfig{}) {
+			pipeline, err = newPipeline(feedConfig.Pipeline, feedConfig.Filter)
+			if err != nil {
+				return fmt.Errorf("feed %s: %w", feedConfig.Path, err)
+			}
+		}
+		sectionFeeds = append(sectionFeeds, &SectionFeed{config: feedConfig, source: source, pipeline: pipeline})
+	}
+	return nil
+}
+
+// Fetch all additional feeds, failed ones keep their previous content
+func refreshSectionFeeds(ctx context.Context) {
+
+	for _, section := range sectionFeeds {
+		nodes, err := section.source.Fetch(ctx)
+		if err != nil {
+			slog.Error("Error while refreshing feed", "path", section.config.Path, "source", section.source.Name(), "error", err)
+			continue
+		}
+		if err := checkUpstreamItems(len(nodes)); err != nil {
+			slog.Warn("Too few articles from upstream, keeping previous feed", "path", section.config.Path, "source", section.source.Name(), "error", err)
+			continue
+		}
+		nodes = checkPublishTimes(nodes, time.Now())
+		rss := buildRss(nodes, section.pipeline)
+		rss.Channel.Title = section.config.Title
+
+		section.Lock()
+		section.rss = rss
+		section.updated = time.Now()
+		section.Unlock()
+		slog.Info("RSS feed generated", "path", section.config.Path, "items", len(rss.Channel.Item))
+	}
+}
+
+func (section *SectionFeed) serve(w http.ResponseWriter, r *http.Request) {
+
+	refreshIfStale()
+	section.RLock()
+	rss, updated := section.rss, section.updated
+	section.RUnlock()
+
+	if updated.IsZero() {
+		w.Header().Set("Retry-After", "5")
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "feed not generated yet", http.StatusServiceUnavailable)
+		return
+	}
+	serveRendered(w, r, &section.rendered, rss, updated)
+}
@@ -0,0 +1,124 @@
+package okorss
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entries not returned by the API for this long are dropped from state
+const seenStateRetention = 30 * 24 * time.Hour
+
+type SeenItem struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Hash      string    `json:"hash"`
+}
+
+// Items already published, by GUID. Kept in memory and optionally saved to
+// the state file, so a restart doesn't announce old items again
+var seenItems = struct {
+	sync.Mutex
+	items    map[string]SeenItem
+	baseline bool
+}{
+	items:    make(map[string]SeenItem),
+	baseline: true,
+}
+
+func loadSeenState(path string) error {
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	seenItems.Lock()
+	defer seenItems.Unlock()
+	err = json.Unmarshal(data, &seenItems.items)
+	if err != nil {
+		return err
+	}
+	if seenItems.items == nil {
+		seenItems.items = make(map[string]SeenItem)
+	}
+	seenItems.baseline = false
+	return nil
+}
+
+// Write state next to the target first, so a crash never leaves it truncated
+func saveSeenState() {
+
+	if config.StateFile == "" {
+		return
+	}
+
+	seenItems.Lock()
+	now := time.Now()
+	for guid, item := range seenItems.items {
+		if now.Sub(item.LastSeen) > seenStateRetention {
+			delete(seenItems.items, guid)
+		}
+	}
+	data, err := json.Marshal(seenItems.items)
+	seenItems.Unlock()
+	if err != nil {
+		slog.Error("Error while encoding state", "error", err)
+		return
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(config.StateFile), ".oko-rss-state-*")
+	if err != nil {
+		slog.Error("Error while saving state", "error", err)
+		return
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Close()
+	} else {
+		temp.Close()
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), config.StateFile)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		slog.Error("Error while saving state", "error", err)
+	}
+}
+
+// Remember article and report whether it's new or changed since it was
+// last seen. Nothing is reported while the baseline is being set
+func markSeen(node Node) string {
+
+	seenItems.Lock()
+	defer seenItems.Unlock()
+
+	guid := nodeGuid(node)
+	hash := contentHash(node)
+	now := time.Now()
+
+	item, ok := seenItems.items[guid]
+	change := ""
+	if !ok {
+		item.FirstSeen = now
+		change = ChangeNew
+	} else if item.Hash != hash {
+		change = ChangeUpdated
+	}
+	item.LastSeen = now
+	item.Hash = hash
+	seenItems.items[guid] = item
+
+	if seenItems.baseline {
+		return ""
+	}
+	return change
+}
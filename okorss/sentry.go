@@ -0,0 +1,111 @@
+package okorss
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentryhttp "github.com/getsentry/sentry-go/http"
+)
+
+type SentryConfig struct {
+	Dsn              string `json:"dsn"`
+	Environment      string `json:"environment"`
+	FailureThreshold int    `json:"failure_threshold"`
+}
+
+// Reporting is on when a DSN is set, in config, SENTRY_DSN or a file
+// named by SENTRY_DSN_FILE
+var sentryEnabled bool
+
+// Failed fetches in a row before an outage is reported
+var sentryFailureThreshold = 3
+
+// Works with any Sentry compatible service, e.g. GlitchTip
+func setupSentry(config SentryConfig) error {
+
+	dsn := config.Dsn
+	if dsn == "" {
+		dsn = os.Getenv("SENTRY_DSN")
+	}
+	if path := os.Getenv("SENTRY_DSN_FILE"); dsn == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dsn = strings.TrimSpace(string(data))
+	}
+	if dsn == "" {
+		return nil
+	}
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         dsn,
+		Environment: config.Environment,
+		Release:     buildVersion(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.FailureThreshold > 0 {
+		sentryFailureThreshold = config.FailureThreshold
+	}
+	sentryEnabled = true
+	return nil
+}
+
+func flushSentry() {
+	if sentryEnabled {
+		sentry.Flush(5 * time.Second)
+	}
+}
+
+// Deferred at the top of long running goroutines. Reports the panic, then
+// lets it crash the process as before
+func reportPanic() {
+	if !sentryEnabled {
+		return
+	}
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	sentry.CurrentHub().Recover(recovered)
+	sentry.Flush(5 * time.Second)
+	panic(recovered)
+}
+
+// Report panics in HTTP handlers, net/http still recovers them afterwards
+func sentryHandler(handler http.Handler) http.Handler {
+	if !sentryEnabled {
+		return handler
+	}
+	return sentryhttp.New(sentryhttp.Options{Repanic: true}).Handle(handler)
+}
+
+// Report an outage once, when fetches have failed often enough in a row
+func reportFetchFailure(err error, failures int) {
+
+	if !sentryEnabled || err == nil || failures != sentryFailureThreshold {
+		return
+	}
+
+	sentry.WithScope(func(scope *sentry.Scope) {
+		context := map[string]interface{}{
+			"url":                  config.Url,
+			"consecutive_failures": failures,
+		}
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			context["status_code"] = upstreamErr.StatusCode
+			context["response_snippet"] = upstreamErr.Snippet
+		}
+		scope.SetContext("upstream", context)
+		scope.SetFingerprint([]string{"fetch-failure"})
+		sentry.CaptureException(err)
+	})
+}
@@ -0,0 +1,544 @@
+package okorss
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Own mux rather than the default one, where imported packages such as
+// net/http/pprof register handlers of their own
+var mux = http.NewServeMux()
+
+// Register content route on the mux, with per path settings
+func handleRoute(path string, handler http.HandlerFunc) {
+	route := otelhttp.WithRouteTag(path, withMetrics(path, withAuth(path, withCacheControl(path, handler))))
+	if path == "/" {
+		rootRoute = route
+		return
+	}
+	mux.Handle(path, route)
+}
+
+// Route registered for "/" itself. The mux pattern "/" matches every path,
+// which is left to serveUnmatched
+var rootRoute http.Handler
+
+// Label in metrics of requests for paths without a route
+const unmatchedRoute = "unmatched"
+
+var notFound = withMetrics(unmatchedRoute, http.NotFound)
+
+func serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" && rootRoute != nil {
+		rootRoute.ServeHTTP(w, r)
+		return
+	}
+	notFound(w, r)
+}
+
+// Register route serving a feed, which is also listed in /feeds.opml
+func handleFeed(path string, title string, handler http.HandlerFunc) {
+	feedRoutes = append(feedRoutes, FeedRoute{Path: path, Title: title})
+	handleRoute(path, handler)
+}
+
+// Register all routes on the mux
+func registerRoutes() error {
+
+	// Anything not routed below is not found
+	mux.HandleFunc("/", serveUnmatched)
+
+	// Serve RSS feed, with a page pointing to it at /
+	handleFeed(mainFeedPath, "OKO.press", serveRss)
+	handleRoute("/", serveLanding)
+
+	// Browser rendering of the feeds
+	handleRoute("/"+feedStylesheetPath, serveStylesheet)
+
+	// Latest articles as a web page
+	page, err := loadHtmlTemplate()
+	if err != nil {
+		return fmt.Errorf("loading HTML template: %w", err)
+	}
+	handleRoute("/html", serveHtml(page))
+
+	// Additional feeds, e.g. OKO.press sections
+	for _, section := range sectionFeeds {
+		handleFeed(section.config.Path, section.config.Title, section.serve)
+	}
+
+	// New articles pushed as they are discovered
+	handleRoute("/ws", serveWebSocket)
+	handleRoute("/poll", servePoll)
+
+	// WebSub hub distributing the feeds to subscribers
+	if config.WebSub.Enabled {
+		handleRoute(webSubHubPath, serveWebSubHub)
+	}
+
+	// Registration for rssCloud notifications
+	if config.Cloud.Enabled {
+		handleRoute(cloudPath, serveCloudNotify)
+	}
+
+	// Serve only new and changed articles from the latest refresh
+	handleRoute("/diff.json", serveDiffJson)
+	handleFeed("/diff.xml", "OKO.press (nowe i zmienione)", serveDiffRss)
+
+	// Serve full history when archive is enabled
+	if archive != nil {
+		handleFeed("/archive.xml", "OKO.press (archiwum)", serveArchiveRss)
+		handleRoute("/stats.json", serveStats)
+	}
+
+	// Feed addresses as QR codes, for sharing in print
+	handleRoute("/qr.png", serveQr)
+
+	// Crawling policy, allowing the feeds
+	handleRoute("/robots.txt", serveRobots)
+
+	// All of the above feeds, for importing into a reader at once
+	handleRoute("/feeds.opml", serveOpml)
+
+	// Refresher state for debugging
+	handleRoute("/status.json", serveStatus)
+
+	// Forced refresh, only with credentials configured for it
+	if hasAuth("/refresh") {
+		handleRoute("/refresh", serveRefresh)
+	}
+
+	// Prometheus scrape endpoint
+	handleRoute("/metrics", serveMetrics)
+
+	// Probes for orchestrators, never behind authentication
+	mux.HandleFunc("/healthz", withMetrics("/healthz", serveHealthz))
+	mux.HandleFunc("/readyz", withMetrics("/readyz", serveReadyz))
+
+	// Profiling, unless it has a port of its own
+	if debugEnabled && config.DebugListen == "" {
+		if !hasAuth(pprofPath) {
+			return fmt.Errorf("refusing to serve profiling without authentication, set auth for %s or debug_listen", pprofPath)
+		}
+		handleRoute(pprofPath, pprofHandler().ServeHTTP)
+	}
+	return nil
+}
+
+// First file descriptor passed by systemd, see sd_listen_fds(3)
+const systemdListenFdsStart = 3
+
+// Take over the socket passed by systemd socket activation, nil when the
+// process wasn't started that way
+func systemdListener() (net.Listener, error) {
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+
+	// Don't pass the sockets on to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if fds > 1 {
+		slog.Warn("Got multiple sockets from systemd, using only the first one", "sockets", fds)
+	}
+	file := os.NewFile(systemdListenFdsStart, "systemd")
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// TCP listener for a secondary server, e.g. the HTTPS redirect, handed
+// over on upgrade like the main one
+func listenTcp(name string, address string) (net.Listener, error) {
+
+	listener, err := inheritedListener(name)
+	if listener == nil && err == nil {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	setUpgradeListener(name, listener)
+	return listener, nil
+}
+
+// Open the configured listener: socket handed over on upgrade, socket
+// passed by systemd when socket activated, "unix:/path/to.sock" for a Unix domain socket, "host:port"
+// for TCP, or the -p port on all interfaces by default
+func listen() (net.Listener, error) {
+
+	listener, err := inheritedListener("http")
+	if listener != nil || err != nil {
+		if err == nil {
+			slog.Info("Using socket passed by previous process")
+		}
+		return listener, err
+	}
+
+	listener, err = systemdListener()
+	if listener != nil || err != nil {
+		if err == nil {
+			slog.Info("Using socket passed by systemd")
+		}
+		return listener, err
+	}
+
+	if !strings.HasPrefix(config.Listen, "unix:") {
+		address := config.Listen
+		if address == "" {
+			address = ":" + port
+		}
+		return net.Listen("tcp", address)
+	}
+
+	// Remove socket left behind by a crashed process
+	path := strings.TrimPrefix(config.Listen, "unix:")
+	info, err := os.Stat(path)
+	if err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err = net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the proxy's group connect, socket_mode is an octal string
+	if config.SocketMode != "" {
+		mode, err := strconv.ParseUint(config.SocketMode, 8, 32)
+		if err == nil {
+			err = os.Chmod(path, os.FileMode(mode))
+		}
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("setting socket mode: %w", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// Serve FastCGI instead of HTTP, for hosting behind Apache or nginx
+var fcgiEnabled bool
+
+// Configured listener, or the socket on stdin when spawned by the web
+// server, e.g. by mod_fcgid
+func fcgiListener() (net.Listener, error) {
+	if config.Listen == "" && os.Getenv("LISTEN_FDS") == "" {
+		listener, err := net.FileListener(os.Stdin)
+		if err == nil {
+			slog.Info("Using FastCGI socket passed on stdin")
+			return listener, nil
+		}
+	}
+	return listen()
+}
+
+// Main feed, with self link pointing to the address the client used
+// Until the first refresh succeeds there's nothing to serve. Requests are
+// never held up by a refresh, they get the feed it replaces instead
+func feedNotReady(w http.ResponseWriter) bool {
+
+	_, updated := currentFeed()
+	if !updated.IsZero() {
+		return false
+	}
+
+	retryAfter := 5 * time.Second
+	next := refreshStatusNext()
+	if wait := time.Until(next); wait > retryAfter {
+		retryAfter = wait
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+	w.Header().Set("Cache-Control", "no-store")
+	http.Error(w, "feed not generated yet", http.StatusServiceUnavailable)
+	return true
+}
+
+func serveRss(w http.ResponseWriter, r *http.Request) {
+
+	if token := r.URL.Query().Get("t"); token != "" {
+		servePersonalRss(w, r, token)
+		return
+	}
+	refreshIfStale()
+	if feedNotReady(w) {
+		return
+	}
+	rss, updated := currentFeed()
+	serveRendered(w, r, &renderedMainFeed, rss, updated)
+}
+
+var renderedMainFeed RenderedFeed
+
+// Feed with links for the request, rendered once per refresh and base URL
+func serveRendered(w http.ResponseWriter, r *http.Request, rendered *RenderedFeed, rss RssFeed, updated time.Time) {
+
+	base := requestBaseUrl(r)
+	rss.Channel.AtomLink = []AtomLink{{Rel: "self", Href: base + r.URL.Path}}
+	rss.Channel.Cloud = feedCloud(r)
+	addHubLinks(w, r, &rss)
+	serveFeed(w, r, rendered, base+r.URL.Path, rss, updated)
+}
+
+// Feed rendered once per key and time it was generated at, answering
+// conditional requests and compressed for clients accepting it
+func serveFeed(w http.ResponseWriter, r *http.Request, rendered *RenderedFeed, key string, rss RssFeed, updated time.Time) {
+
+	variant, err := rendered.get(key, updated, func(w io.Writer) error {
+		return writeRss(w, rss, updated, requestBasePath(r)+"/"+feedStylesheetPath)
+	})
+	if err != nil {
+		slog.Error("Error while rendering feed", "path", r.URL.Path, "error", err)
+		http.Error(w, "feed unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", feedContentType())
+	variant.write(w, r, updated)
+}
+
+// TLS 1.2+ with forward secrecy and AEAD ciphers only. TLS 1.3 suites
+// aren't configurable and are all fine
+func tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+	}
+}
+
+type AcmeConfig struct {
+	Domains      []string `json:"domains"`
+	CacheDir     string   `json:"cache_dir"`
+	Email        string   `json:"email"`
+	DirectoryUrl string   `json:"directory_url"`
+}
+
+// Obtain and renew certificates from Let's Encrypt, or another ACME CA,
+// for the listed domains only. Certificates are kept in the cache dir so
+// restarts don't hit the CA's rate limits
+func acmeManager(config AcmeConfig) *autocert.Manager {
+
+	cacheDir := config.CacheDir
+	if cacheDir == "" {
+		cacheDir = "acme-cache"
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(config.Domains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      config.Email,
+	}
+	if config.DirectoryUrl != "" {
+		manager.Client = &acme.Client{DirectoryURL: config.DirectoryUrl}
+	}
+	return manager
+}
+
+// Port HTTPS is served on, from listen when it's a TCP address, the -p
+// port otherwise
+func httpsPort() string {
+	if config.Listen != "" && !strings.HasPrefix(config.Listen, "unix:") {
+		_, listenPort, err := net.SplitHostPort(config.Listen)
+		if err == nil && listenPort != "" {
+			return listenPort
+		}
+	}
+	return port
+}
+
+// Send plain HTTP clients to the HTTPS listener
+func redirectToHttps(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if tlsPort := httpsPort(); tlsPort != "443" {
+		host = net.JoinHostPort(host, tlsPort)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
+
+func serveHttp(ctx context.Context, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+	defer reportPanic()
+
+	slog.Info("Starting HTTP server")
+	err := registerRoutes()
+	if err != nil {
+		fatal("Error while registering routes", "error", err)
+	}
+
+	err = setupTrustedProxies()
+	if err != nil {
+		fatal("Error while parsing trusted proxies", "error", err)
+	}
+
+	var listener net.Listener
+	if fcgiEnabled {
+		listener, err = fcgiListener()
+	} else {
+		listener, err = listen()
+	}
+	if err != nil {
+		fatal("Error while opening listener", "error", err)
+	}
+	setUpgradeListener("http", listener)
+	listener = limitConnections(listener, config.ServerLimits)
+
+	handler := otelhttp.NewHandler(accessLog(rateLimit(config.RateLimit, sentryHandler(mux))), "serve")
+	server := limitedServer(handler, config.ServerLimits)
+	servers := []*http.Server{server}
+	serverErr := make(chan error, 2)
+
+	// Redirect listener doubles as HTTP-01 challenge responder under ACME
+	var redirectHandler http.Handler = http.HandlerFunc(redirectToHttps)
+	useTls := !fcgiEnabled && (config.TlsCert != "" || len(config.Acme.Domains) > 0)
+
+	if fcgiEnabled {
+		// The web server in front terminates TLS and keeps connections
+		go func() {
+			serverErr <- fcgi.Serve(listener, handler)
+		}()
+	} else if len(config.Acme.Domains) > 0 {
+		manager := acmeManager(config.Acme)
+		server.TLSConfig = tlsConfig()
+		server.TLSConfig.GetCertificate = manager.GetCertificate
+		server.TLSConfig.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+		redirectHandler = manager.HTTPHandler(redirectHandler)
+		go func() {
+			serverErr <- server.ServeTLS(listener, "", "")
+		}()
+	} else if config.TlsCert != "" {
+		server.TLSConfig = tlsConfig()
+		go func() {
+			serverErr <- server.ServeTLS(listener, config.TlsCert, config.TlsKey)
+		}()
+	} else {
+		go func() {
+			serverErr <- server.Serve(listener)
+		}()
+	}
+
+	// Optional second listener redirecting to HTTPS
+	if useTls && config.HttpRedirect != "" {
+		redirectListener, err := listenTcp("redirect", config.HttpRedirect)
+		if err != nil {
+			fatal("Error while opening redirect listener", "error", err)
+		}
+		redirect := limitedServer(redirectHandler, config.ServerLimits)
+		servers = append(servers, redirect)
+		go func() {
+			serverErr <- redirect.Serve(redirectListener)
+		}()
+	}
+
+	// Every listener is bound by now, the debug one before this started
+	notifyUpgradeReady()
+
+	select {
+	case err := <-serverErr:
+		fatal("Error while serving HTTP content", "error", err)
+	case <-ctx.Done():
+	}
+
+	// FastCGI has no graceful shutdown, requests in flight are cut off
+	slog.Info("Stopping HTTP server")
+	if fcgiEnabled {
+		listener.Close()
+		return
+	}
+
+	// Stop accepting connections and let in-flight requests finish
+	closeWebSockets()
+	stopPolls()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(config))
+	defer cancel()
+	for _, server := range servers {
+		err = server.Shutdown(shutdownCtx)
+		if err != nil {
+			slog.Error("Error while stopping HTTP server", "error", err)
+		}
+	}
+}
+
+// Time given to in-flight requests on shutdown
+func shutdownTimeout(config Config) time.Duration {
+	if config.ShutdownTimeout > 0 {
+		return time.Duration(config.ShutdownTimeout)
+	}
+	return 10 * time.Second
+}
+
+// Address of this instance as seen by the client, including the path it's
+// mounted under. Behind a trusted proxy the scheme and host it reports take
+// precedence
+func requestBaseUrl(r *http.Request) string {
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	if fromTrustedProxy(r) {
+		proto := firstForwarded(r.Header.Get("X-Forwarded-Proto"))
+		if proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		forwardedHost := firstForwarded(r.Header.Get("X-Forwarded-Host"))
+		if forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	return scheme + "://" + host + requestBasePath(r)
+}
+
+// Path the routes are mounted under, without a trailing slash. A trusted
+// proxy stripping a prefix reports it in X-Forwarded-Prefix
+func requestBasePath(r *http.Request) string {
+
+	prefix := config.BasePath
+	if fromTrustedProxy(r) {
+		forwardedPrefix := firstForwarded(r.Header.Get("X-Forwarded-Prefix"))
+		if forwardedPrefix != "" {
+			prefix = forwardedPrefix
+		}
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return ""
+	}
+	return strings.TrimRight(prefix, "/")
+}
@@ -0,0 +1,106 @@
+package okorss
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config file of serverless deployments, where there are no arguments
+const serverlessConfigEnv = "OKO_RSS_CONFIG"
+
+// How long a generated feed stays current, e.g. across invocations of a
+// warm serverless instance: max_age or interval when set
+func feedCacheAge() time.Duration {
+	if config.MaxAge > 0 {
+		return time.Duration(config.MaxAge)
+	}
+	if config.Interval > 0 {
+		return time.Duration(config.Interval)
+	}
+	return 5 * time.Minute
+}
+
+// Serverless platforms freeze instances between invocations, so there's
+// no refresher in the background. The feed is generated within the
+// request instead, when the cached one has expired
+func withServerlessRefresh(handler http.Handler) http.Handler {
+
+	var mutex sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		mutex.Lock()
+		_, updated := currentFeed()
+		if time.Since(updated) > feedCacheAge() {
+			_, err := refresh(r.Context())
+			logRefreshError(err)
+		}
+		mutex.Unlock()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Generate the feed on request, as an AWS Lambda function when run by
+// Lambda, otherwise on $PORT as Google Cloud Functions and Cloud Run
+// expect
+func serverlessCommand(args []string) {
+
+	configPath := os.Getenv(serverlessConfigEnv)
+	if configPath == "" {
+		configPath = "config.json"
+	}
+	flags := flag.NewFlagSet("serverless", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", configPath, "config file path")
+	flags.StringVar(&configPath, "config", configPath, "config file path")
+	flags.Parse(args)
+
+	loadConfig(configPath)
+	err := setupFeed()
+	if err != nil {
+		fatal("Error while setting up feed", "error", err)
+	}
+
+	err = setupSentry(config.Sentry)
+	if err != nil {
+		fatal("Error while setting up Sentry", "error", err)
+	}
+	defer flushSentry()
+	defer reportPanic()
+
+	err = registerRoutes()
+	if err != nil {
+		fatal("Error while registering routes", "error", err)
+	}
+	err = setupTrustedProxies()
+	if err != nil {
+		fatal("Error while parsing trusted proxies", "error", err)
+	}
+	handler := accessLog(withServerlessRefresh(sentryHandler(mux)))
+
+	if runningServerless() {
+		startLambda(handler)
+		return
+	}
+
+	address := ":" + os.Getenv("PORT")
+	if address == ":" {
+		address = ":8080"
+	}
+	slog.Info("Starting serverless HTTP handler", "address", address)
+	server := limitedServer(handler, config.ServerLimits)
+	server.Addr = address
+	err = server.ListenAndServe()
+	if err != nil {
+		fatal("Error while serving HTTP content", "error", err)
+	}
+}
+
+// Serverless platforms don't pass arguments, Lambda is recognized by its
+// runtime API
+func runningServerless() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != ""
+}
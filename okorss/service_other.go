@@ -0,0 +1,17 @@
+//go:build !windows
+
+package okorss
+
+import (
+	"context"
+)
+
+// Services are a Windows thing, elsewhere systemd or similar run the
+// process as is
+func serviceContext(ctx context.Context) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func serviceCommand(args []string) {
+	fatal("The service command is only available on Windows")
+}
@@ -0,0 +1,240 @@
+//go:build windows
+
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Name the service is installed under, also its event log source
+const serviceName = "oko-press-rss"
+
+// Handles requests of the service manager. Stopping the service cancels
+// the context and waits for the shutdown to finish
+type windowsService struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (service *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+
+	status <- svc.Status{State: svc.StartPending}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(shutdownTimeout(config).Milliseconds())}
+				service.cancel()
+				<-service.done
+				return false, 0
+			}
+		case <-service.done:
+			return false, 0
+		}
+	}
+}
+
+// Writes log records to the Windows event log, at the level set up before
+type eventLogHandler struct {
+	slog.Handler
+	log     *eventlog.Log
+	enabled slog.Handler
+	mutex   *sync.Mutex
+	buffer  *bytes.Buffer
+}
+
+func newEventLogHandler(log *eventlog.Log) *eventLogHandler {
+	buffer := &bytes.Buffer{}
+	return &eventLogHandler{
+		Handler: slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		log:     log,
+		enabled: slog.Default().Handler(),
+		mutex:   &sync.Mutex{},
+		buffer:  buffer,
+	}
+}
+
+func (handler *eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.enabled.Enabled(ctx, level)
+}
+
+func (handler *eventLogHandler) Handle(ctx context.Context, record slog.Record) error {
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	handler.buffer.Reset()
+	err := handler.Handler.Handle(ctx, record)
+	if err != nil {
+		return err
+	}
+	message := handler.buffer.String()
+	switch {
+	case record.Level >= slog.LevelError:
+		return handler.log.Error(1, message)
+	case record.Level >= slog.LevelWarn:
+		return handler.log.Warning(1, message)
+	default:
+		return handler.log.Info(1, message)
+	}
+}
+
+func (handler *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	copy := *handler
+	copy.Handler = handler.Handler.WithAttrs(attrs)
+	return &copy
+}
+
+func (handler *eventLogHandler) WithGroup(name string) slog.Handler {
+	copy := *handler
+	copy.Handler = handler.Handler.WithGroup(name)
+	return &copy
+}
+
+// When started by the service manager, returns a context cancelled when
+// the service is stopped, and a function to call once shut down. Logs go
+// to the event log then, unless log_output points elsewhere
+func serviceContext(ctx context.Context) (context.Context, func()) {
+
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return ctx, func() {}
+	}
+
+	if config.LogOutput == "" || config.LogOutput == "stderr" {
+		log, err := eventlog.Open(serviceName)
+		if err == nil {
+			slog.SetDefault(slog.New(newEventLogHandler(log)))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	service := &windowsService{cancel: cancel, done: make(chan struct{})}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		err := svc.Run(serviceName, service)
+		if err != nil {
+			slog.Error("Error while running as service", "error", err)
+			cancel()
+		}
+	}()
+
+	return ctx, func() {
+		close(service.done)
+		<-stopped
+	}
+}
+
+// Manage the Windows service: install, uninstall, start or stop. Install
+// takes the config the service runs with, and the port
+func serviceCommand(args []string) {
+
+	if len(args) == 0 {
+		fmt.Println("Usage: oko-rss service install|uninstall|start|stop [options]")
+		os.Exit(2)
+	}
+
+	var configPath, servicePort string
+	flags := flag.NewFlagSet("service", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "", "config file path")
+	flags.StringVar(&configPath, "config", "", "config file path")
+	flags.StringVar(&servicePort, "p", "8000", "port number")
+	flags.StringVar(&servicePort, "port", "8000", "port number")
+	flags.Parse(args[1:])
+
+	manager, err := mgr.Connect()
+	if err != nil {
+		fatal("Error while connecting to service manager", "error", err)
+	}
+	defer manager.Disconnect()
+
+	switch args[0] {
+	case "install":
+		err = installService(manager, configPath, servicePort)
+	case "uninstall":
+		err = uninstallService(manager)
+	case "start":
+		err = withService(manager, func(service *mgr.Service) error {
+			return service.Start()
+		})
+	case "stop":
+		err = withService(manager, func(service *mgr.Service) error {
+			_, err := service.Control(svc.Stop)
+			return err
+		})
+	default:
+		err = fmt.Errorf("unknown service command %q", args[0])
+	}
+	if err != nil {
+		fatal("Error while managing service", "command", args[0], "error", err)
+	}
+	fmt.Println("Service", args[0], "done")
+}
+
+func installService(manager *mgr.Mgr, configPath string, servicePort string) error {
+
+	if configPath == "" {
+		return fmt.Errorf("config path is required")
+	}
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	service, err := manager.CreateService(serviceName, executable, mgr.Config{
+		DisplayName: "OKO.press RSS",
+		Description: "RSS feed of OKO.press articles",
+		StartType:   mgr.StartAutomatic,
+	}, "-c", configPath, "-p", servicePort)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+
+	err = eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil {
+		service.Delete()
+		return fmt.Errorf("registering event log source: %w", err)
+	}
+	return nil
+}
+
+func uninstallService(manager *mgr.Mgr) error {
+	err := withService(manager, func(service *mgr.Service) error {
+		return service.Delete()
+	})
+	if err != nil {
+		return err
+	}
+	return eventlog.Remove(serviceName)
+}
+
+func withService(manager *mgr.Mgr, action func(service *mgr.Service) error) error {
+	service, err := manager.OpenService(serviceName)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	return action(service)
+}
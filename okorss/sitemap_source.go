@@ -0,0 +1,267 @@
+package okorss
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Sitemap or sitemap index, with the news and image extensions. Elements
+// are matched by local name, whatever their namespace
+type Sitemap struct {
+	Urls    []SitemapUrl `xml:"url"`
+	Indexed []SitemapUrl `xml:"sitemap"`
+}
+
+type SitemapUrl struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod"`
+	News    struct {
+		Title           string `xml:"title"`
+		PublicationDate string `xml:"publication_date"`
+	} `xml:"news"`
+	Image struct {
+		Loc string `xml:"loc"`
+	} `xml:"image"`
+}
+
+// Title, lead and image read from the article page itself
+type PageMeta struct {
+	Lastmod string
+	Title   string
+	Lead    string
+	Image   string
+}
+
+// Articles listed in a sitemap, newest first. Titles come from the news
+// extension or the address, unless fetch_pages reads them from the pages
+type SitemapSource struct {
+	config SourceConfig
+
+	// Pages are fetched again only when their lastmod changes
+	mutex sync.Mutex
+	pages map[string]PageMeta
+}
+
+func newSitemapSource(config Config) (Source, error) {
+	source := config.Source
+	if source.Url == "" {
+		return nil, fmt.Errorf("source url is required for the sitemap source")
+	}
+	if source.Limit == 0 {
+		source.Limit = 20
+	}
+	if source.Concurrency < 1 {
+		source.Concurrency = 4
+	}
+	return &SitemapSource{config: source, pages: make(map[string]PageMeta)}, nil
+}
+
+func (source *SitemapSource) Name() string {
+	return "sitemap " + source.config.Url
+}
+
+func (source *SitemapSource) fetchSitemap(ctx context.Context, url string) (Sitemap, error) {
+
+	var sitemap Sitemap
+	body, err := fetchBody(ctx, url, source.config.Headers)
+	if err != nil {
+		return sitemap, err
+	}
+	err = xml.Unmarshal(body, &sitemap)
+	if err != nil {
+		return sitemap, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("parsing sitemap: %w", err)}
+	}
+	return sitemap, nil
+}
+
+func (source *SitemapSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	sitemap, err := source.fetchSitemap(ctx, source.config.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Of an index only the most recently modified sitemap is read, that's
+	// where new articles are
+	if len(sitemap.Indexed) > 0 {
+		sort.SliceStable(sitemap.Indexed, func(i, j int) bool {
+			return sitemapTime(sitemap.Indexed[i].Lastmod).After(sitemapTime(sitemap.Indexed[j].Lastmod))
+		})
+		sitemap, err = source.fetchSitemap(ctx, strings.TrimSpace(sitemap.Indexed[0].Loc))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	urls := sitemap.Urls
+	sort.SliceStable(urls, func(i, j int) bool {
+		return urls[i].published().After(urls[j].published())
+	})
+	if len(urls) > source.config.Limit {
+		urls = urls[:source.config.Limit]
+	}
+
+	nodes := make([]Node, len(urls))
+	for i, entry := range urls {
+		nodes[i] = entry.node()
+	}
+	if source.config.FetchPages {
+		source.fillFromPages(ctx, nodes, urls)
+	}
+
+	// Forget pages that dropped out of the sitemap
+	source.mutex.Lock()
+	for link := range source.pages {
+		if !containsNode(nodes, link) {
+			delete(source.pages, link)
+		}
+	}
+	source.mutex.Unlock()
+
+	return nodes, nil
+}
+
+func containsNode(nodes []Node, link string) bool {
+	for _, node := range nodes {
+		if node.Link == link {
+			return true
+		}
+	}
+	return false
+}
+
+// Lastmod is a W3C datetime, down to just the date
+func sitemapTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+func (entry SitemapUrl) published() time.Time {
+	published := sitemapTime(entry.News.PublicationDate)
+	if published.IsZero() {
+		published = sitemapTime(entry.Lastmod)
+	}
+	return published
+}
+
+// Title made of the last path segment, e.g. "nowy-rzad-zaprzysiezony"
+func titleFromUrl(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	slug := path.Base(strings.TrimSuffix(parsed.Path, "/"))
+	slug = strings.TrimSuffix(slug, path.Ext(slug))
+	title := strings.Join(strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' }), " ")
+	if title == "" {
+		return link
+	}
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
+
+func (entry SitemapUrl) node() Node {
+
+	var node Node
+	node.Link = strings.TrimSpace(entry.Loc)
+	node.ID = node.Link
+	node.Title = strings.TrimSpace(entry.News.Title)
+	if node.Title == "" {
+		node.Title = titleFromUrl(node.Link)
+	}
+	node.Image.Url = strings.TrimSpace(entry.Image.Loc)
+	if published := entry.published(); !published.IsZero() {
+		node.Published = apiTime(published)
+	}
+	return node
+}
+
+// Fill nodes from their pages, a few at a time. Pages not started before
+// the context is done keep what the sitemap had
+func (source *SitemapSource) fillFromPages(ctx context.Context, nodes []Node, urls []SitemapUrl) {
+
+	slots := make(chan struct{}, source.config.Concurrency)
+	var wg sync.WaitGroup
+	for i := range nodes {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			source.fillFromPage(ctx, &nodes[i], urls[i])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Take title, lead and image from the page's Open Graph tags. A page that
+// can't be fetched keeps what the sitemap had
+func (source *SitemapSource) fillFromPage(ctx context.Context, node *Node, entry SitemapUrl) {
+
+	source.mutex.Lock()
+	meta, ok := source.pages[node.Link]
+	source.mutex.Unlock()
+
+	if !ok || meta.Lastmod != entry.Lastmod {
+		body, err := fetchBody(ctx, node.Link, source.config.Headers)
+		if err != nil {
+			slog.Warn("Error while fetching page from sitemap", "url", node.Link, "error", err)
+			return
+		}
+		document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			slog.Warn("Error while parsing page from sitemap", "url", node.Link, "error", err)
+			return
+		}
+		property := func(name string) string {
+			value, _ := document.Find(`meta[property="` + name + `"]`).Attr("content")
+			return strings.TrimSpace(value)
+		}
+		meta = PageMeta{
+			Lastmod: entry.Lastmod,
+			Title:   property("og:title"),
+			Lead:    property("og:description"),
+			Image:   property("og:image"),
+		}
+		if meta.Title == "" {
+			meta.Title = strings.TrimSpace(document.Find("title").First().Text())
+		}
+
+		source.mutex.Lock()
+		source.pages[node.Link] = meta
+		source.mutex.Unlock()
+	}
+
+	if meta.Title != "" {
+		node.Title = meta.Title
+	}
+	if meta.Image != "" {
+		node.Image.Url = meta.Image
+	}
+	node.Lead = meta.Lead
+}
@@ -0,0 +1,24 @@
+package okorss
+
+import "testing"
+
+func TestTitleFromUrl(t *testing.T) {
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://oko.press/nowy-rzad-zaprzysiezony", "Nowy rzad zaprzysiezony"},
+		{"https://oko.press/nowy-rzad/", "Nowy rzad"},
+		{"https://example.com/news/snake_case_title.html", "Snake case title"},
+		{"https://oko.press/żurek-w-sejmie", "Żurek w sejmie"},
+		{"https://oko.press/", "https://oko.press/"},
+		{"https://oko.press/---", "https://oko.press/---"},
+	}
+
+	for _, test := range tests {
+		if got := titleFromUrl(test.link); got != test.want {
+			t.Errorf("titleFromUrl(%q) = %q, want %q", test.link, got, test.want)
+		}
+	}
+}
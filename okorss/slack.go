@@ -0,0 +1,143 @@
+package okorss
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SlackRoute struct {
+	Webhook    string   `json:"webhook"`
+	Categories []string `json:"categories"`
+}
+
+type SlackConfig struct {
+	Enabled    bool         `json:"enabled"`
+	Routes     []SlackRoute `json:"routes"`
+	QuietHours QuietHours   `json:"quiet_hours"`
+}
+
+// Items announced during quiet hours are held back and sent together once
+// the window ends
+type SlackNotifier struct {
+	config SlackConfig
+
+	mutex          sync.Mutex
+	pending        []Node
+	flushScheduled bool
+}
+
+func newSlackNotifier(config SlackConfig) (*SlackNotifier, error) {
+	err := config.QuietHours.Setup()
+	if err != nil {
+		return nil, err
+	}
+	return &SlackNotifier{config: config}, nil
+}
+
+func (slack *SlackNotifier) Name() string {
+	return "Slack"
+}
+
+func slackBlocks(nodes []Node) []interface{} {
+
+	var blocks []interface{}
+	for _, node := range nodes {
+		item := newNotifyItem(node)
+
+		section := map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{
+				"type": "mrkdwn",
+				"text": "*<" + item.Link + "|" + slackEscape(item.Title) + ">*\n" + slackEscape(item.Lead),
+			},
+		}
+		if item.Image != "" {
+			section["accessory"] = map[string]string{
+				"type":      "image",
+				"image_url": item.Image,
+				"alt_text":  item.Title,
+			}
+		}
+		blocks = append(blocks, section)
+	}
+	return blocks
+}
+
+// Slack mrkdwn treats these three characters as control sequences
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(text string) string {
+	return slackEscaper.Replace(text)
+}
+
+// Slack rejects messages with more than 50 blocks
+const slackMaxBlocks = 50
+
+func (slack *SlackNotifier) send(nodes []Node) error {
+
+	var lastErr error
+	for _, route := range slack.config.Routes {
+
+		var routed []Node
+		for _, node := range nodes {
+			if len(route.Categories) == 0 || inAnyCategory(node, route.Categories) {
+				routed = append(routed, node)
+			}
+		}
+
+		for start := 0; start < len(routed); start += slackMaxBlocks {
+			end := start + slackMaxBlocks
+			if end > len(routed) {
+				end = len(routed)
+			}
+			message := map[string]interface{}{
+				"text":   newNotifyItem(routed[start]).Title,
+				"blocks": slackBlocks(routed[start:end]),
+			}
+			err := retry(3, 5*time.Second, func() error {
+				return sendJson("POST", route.Webhook, nil, message, nil)
+			})
+			if err != nil {
+				lastErr = err
+				break
+			}
+		}
+	}
+	return lastErr
+}
+
+func (slack *SlackNotifier) Notify(nodes []Node) error {
+
+	slack.mutex.Lock()
+	defer slack.mutex.Unlock()
+
+	wait := slack.config.QuietHours.Remaining(time.Now())
+	if wait == 0 {
+		return slack.send(nodes)
+	}
+
+	slack.pending = append(slack.pending, nodes...)
+	if !slack.flushScheduled {
+		slack.flushScheduled = true
+		time.AfterFunc(wait, slack.flush)
+	}
+	return nil
+}
+
+// Send everything held back during quiet hours
+func (slack *SlackNotifier) flush() {
+
+	slack.mutex.Lock()
+	defer slack.mutex.Unlock()
+
+	pending := slack.pending
+	slack.pending = nil
+	slack.flushScheduled = false
+
+	err := slack.send(pending)
+	if err != nil {
+		slog.Error("Error while notifying", "notifier", slack.Name(), "error", err)
+	}
+}
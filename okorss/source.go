@@ -0,0 +1,193 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Where articles come from. Implementations map their upstream format onto
+// Node, the internal item model, so feed generation, archiving and serving
+// don't depend on it
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context) ([]Node, error)
+}
+
+// Selects and configures the source, OKO.press by default. Sources other
+// than OKO.press take their URL from here
+type SourceConfig struct {
+	Type    string            `json:"type"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Limit   int               `json:"limit"`
+
+	// OKO.press section, or API filter, for the "okopress" source
+	Section string          `json:"section"`
+	Where   json.RawMessage `json:"where"`
+
+	// Content API key for the "ghost" source
+	Key string `json:"key"`
+
+	// Read titles from the pages listed by the "sitemap" source, this many
+	// at once
+	FetchPages  bool `json:"fetch_pages"`
+	Concurrency int  `json:"concurrency"`
+
+	// Selectors for the "html" source
+	Selectors HtmlSelectors `json:"selectors"`
+
+	// Program run by the "plugin" source
+	PluginConfig
+
+	// Source used when this one fails or returns nothing
+	Fallback *SourceConfig `json:"fallback"`
+
+	// Sources merged by the "multi" source, each with a label and a cap
+	Sources  []SourceConfig `json:"sources"`
+	Label    string         `json:"label"`
+	MaxItems int            `json:"max_items"`
+
+	// Mapping for the "json" source
+	Items      string     `json:"items"`
+	Fields     JsonFields `json:"fields"`
+	DateFormat string     `json:"date_format"`
+}
+
+// Constructors of available source types
+var sourceTypes = map[string]func(config Config) (Source, error){
+	"okopress":  newOkoPressSource,
+	"json":      newJsonSource,
+	"wordpress": newWordPressSource,
+	"ghost":     newGhostSource,
+	"html":      newHtmlSource,
+	"sitemap":   newSitemapSource,
+	"plugin":    newPluginSource,
+}
+
+// Configured source, set up at start
+var source Source
+
+func setupSource(config Config) error {
+	var err error
+	source, err = newSource(config)
+	return err
+}
+
+func newSource(config Config) (Source, error) {
+
+	sourceType := config.Source.Type
+	if sourceType == "" {
+		sourceType = "okopress"
+	}
+	// Not in sourceTypes, as it builds sources itself
+	constructor, ok := sourceTypes[sourceType]
+	if sourceType == "multi" {
+		constructor, ok = newMultiSource, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q", sourceType)
+	}
+	primary, err := constructor(config)
+	if err != nil || config.Source.Fallback == nil {
+		return primary, err
+	}
+
+	fallbackConfig := config
+	fallbackConfig.Source = *config.Source.Fallback
+	fallback, err := newSource(fallbackConfig)
+	if err != nil {
+		return nil, fmt.Errorf("fallback source: %w", err)
+	}
+	return FallbackSource{Primary: primary, Fallback: fallback, primaryIds: &primaryIds{}}, nil
+}
+
+// Tries the fallback when the primary source fails or comes back empty,
+// e.g. after an API change
+type FallbackSource struct {
+	Primary    Source
+	Fallback   Source
+	primaryIds *primaryIds
+}
+
+// IDs of the primary's articles by link, from its last successful fetch
+type primaryIds struct {
+	sync.Mutex
+	byLink map[string]string
+}
+
+func (source FallbackSource) Name() string {
+	return source.Primary.Name()
+}
+
+func (source FallbackSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	nodes, err := source.Primary.Fetch(ctx)
+	if err == nil && len(nodes) > 0 {
+		source.primaryIds.Lock()
+		source.primaryIds.byLink = make(map[string]string, len(nodes))
+		for _, node := range nodes {
+			source.primaryIds.byLink[nodeLink(node)] = node.ID
+		}
+		source.primaryIds.Unlock()
+		return nodes, nil
+	}
+	slog.Warn("Primary source failed, using fallback", "source", source.Primary.Name(), "fallback", source.Fallback.Name(), "items", len(nodes), "error", err)
+
+	fallbackNodes, fallbackErr := source.Fallback.Fetch(ctx)
+	if fallbackErr != nil {
+		if err == nil {
+			return nodes, nil
+		}
+		return nil, errors.Join(err, fmt.Errorf("fallback: %w", fallbackErr))
+	}
+
+	// Articles the primary returned before keep their IDs, so GUIDs don't
+	// change with a failover
+	source.primaryIds.Lock()
+	defer source.primaryIds.Unlock()
+	for i := range fallbackNodes {
+		if id, ok := source.primaryIds.byLink[nodeLink(fallbackNodes[i])]; ok {
+			fallbackNodes[i].ID = id
+		}
+		fallbackNodes[i].Fallback = true
+	}
+	return fallbackNodes, nil
+}
+
+// Time in the format of Node.Published, as the OKO.press API returns it
+func apiTime(published time.Time) string {
+	return published.UTC().Format("2006-01-02T15:04:05")
+}
+
+// OKO.press GraphQL API, paged through the "variables" query parameter
+type OkoPressSource struct {
+	Url string
+}
+
+func newOkoPressSource(config Config) (Source, error) {
+	url := config.Source.Url
+	if url == "" {
+		url = config.Url
+	}
+	if url == "" {
+		return nil, fmt.Errorf("url is required for the okopress source")
+	}
+	url, err := sectionUrl(url, config.Source.Section, config.Source.Where)
+	if err != nil {
+		return nil, err
+	}
+	return OkoPressSource{Url: url}, nil
+}
+
+func (oko OkoPressSource) Name() string {
+	return "OKO.press"
+}
+
+func (oko OkoPressSource) Fetch(ctx context.Context) ([]Node, error) {
+	return fetchNodes(ctx, oko.Url)
+}
@@ -0,0 +1,111 @@
+package okorss
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// How far back the per day and per week histograms reach
+const (
+	statsDays        = 30
+	statsWeeks       = 12
+	statsFetchWindow = 7 * 24 * time.Hour
+)
+
+type Stats struct {
+	Articles        int            `json:"articles"`
+	LatestPublished string         `json:"latest_published"`
+	PerDay          map[string]int `json:"per_day"`
+	PerWeek         map[string]int `json:"per_week"`
+	Categories      map[string]int `json:"categories"`
+	Fetches         struct {
+		Since       time.Time `json:"since"`
+		Total       int       `json:"total"`
+		Succeeded   int       `json:"succeeded"`
+		SuccessRate float64   `json:"success_rate"`
+	} `json:"fetches"`
+}
+
+// Run a query returning label and count pairs
+func countBy(db *sql.DB, query string, args ...interface{}) (map[string]int, error) {
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var label string
+		var count int
+		err = rows.Scan(&label, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[label] = count
+	}
+	return counts, rows.Err()
+}
+
+func archiveStats(db *sql.DB) (Stats, error) {
+
+	var stats Stats
+	now := time.Now().UTC()
+
+	err := db.QueryRow("SELECT COUNT(*), COALESCE(MAX(published), '') FROM articles").Scan(&stats.Articles, &stats.LatestPublished)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.PerDay, err = countBy(db, `SELECT date(published), COUNT(*) FROM articles
+		WHERE published >= ? GROUP BY 1`, now.AddDate(0, 0, -statsDays).Format("2006-01-02"))
+	if err != nil {
+		return stats, err
+	}
+
+	stats.PerWeek, err = countBy(db, `SELECT strftime('%Y-W%W', published), COUNT(*) FROM articles
+		WHERE published >= ? GROUP BY 1`, now.AddDate(0, 0, -7*statsWeeks).Format("2006-01-02"))
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Categories, err = countBy(db, `SELECT json_extract(category.value, '$.name'), COUNT(*)
+		FROM articles, json_each(articles.raw, '$.categories') AS category
+		WHERE json_extract(category.value, '$.name') IS NOT NULL GROUP BY 1`)
+	if err != nil {
+		return stats, err
+	}
+
+	fetches := &stats.Fetches
+	fetches.Since = now.Add(-statsFetchWindow)
+	err = db.QueryRow("SELECT COUNT(*), COALESCE(SUM(ok), 0) FROM fetches WHERE time >= ?",
+		fetches.Since.Format(time.RFC3339)).Scan(&fetches.Total, &fetches.Succeeded)
+	if err != nil {
+		return stats, err
+	}
+	if fetches.Total > 0 {
+		fetches.SuccessRate = float64(fetches.Succeeded) / float64(fetches.Total)
+	}
+
+	return stats, nil
+}
+
+func serveStats(w http.ResponseWriter, r *http.Request) {
+
+	stats, err := archiveStats(archive)
+	if err != nil {
+		slog.Error("Error while computing statistics", "path", r.URL.Path, "error", err)
+		http.Error(w, "statistics unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	encoder.Encode(stats)
+}
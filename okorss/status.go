@@ -0,0 +1,191 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Number of recent fetches kept for /status.json
+const fetchHistorySize = 20
+
+type FetchRecord struct {
+	Time       time.Time `json:"time"`
+	DurationMs int64     `json:"duration_ms"`
+	Ok         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	Items      int       `json:"items"`
+}
+
+// Refresher state, kept in memory so it works without an archive
+var refreshStatus struct {
+	sync.Mutex
+	history             []FetchRecord
+	consecutiveFailures int
+	nextRefresh         time.Time
+}
+
+// Remember fetch outcome, returns number of failures in a row
+func recordFetchStatus(start time.Time, items int, err error) int {
+
+	record := FetchRecord{
+		Time:       start,
+		DurationMs: time.Since(start).Milliseconds(),
+		Ok:         err == nil,
+		Items:      items,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	refreshStatus.Lock()
+	defer refreshStatus.Unlock()
+
+	if err != nil {
+		refreshStatus.consecutiveFailures++
+	} else {
+		refreshStatus.consecutiveFailures = 0
+	}
+
+	refreshStatus.history = append(refreshStatus.history, record)
+	if len(refreshStatus.history) > fetchHistorySize {
+		refreshStatus.history = refreshStatus.history[len(refreshStatus.history)-fetchHistorySize:]
+	}
+	return refreshStatus.consecutiveFailures
+}
+
+func consecutiveFailures() int {
+	refreshStatus.Lock()
+	defer refreshStatus.Unlock()
+	return refreshStatus.consecutiveFailures
+}
+
+// Start of the most recent fetch, successful or not
+func lastFetchTime() time.Time {
+	refreshStatus.Lock()
+	defer refreshStatus.Unlock()
+	if len(refreshStatus.history) == 0 {
+		return time.Time{}
+	}
+	return refreshStatus.history[len(refreshStatus.history)-1].Time
+}
+
+func refreshStatusNext() time.Time {
+	refreshStatus.Lock()
+	defer refreshStatus.Unlock()
+	return refreshStatus.nextRefresh
+}
+
+func setNextRefresh(next time.Time) {
+	refreshStatus.Lock()
+	refreshStatus.nextRefresh = next
+	refreshStatus.Unlock()
+}
+
+// Version given at build time, or module version and VCS revision
+// recorded by the Go toolchain
+func buildVersion() string {
+
+	if version != "" {
+		return version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	result := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			result += " (" + setting.Value + ")"
+		}
+	}
+	return result
+}
+
+type Status struct {
+	Version             string        `json:"version"`
+	FeedUpdated         *time.Time    `json:"feed_updated"`
+	FeedItems           int           `json:"feed_items"`
+	LastFetch           *FetchRecord  `json:"last_fetch"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	NextRefresh         *time.Time    `json:"next_refresh"`
+	History             []FetchRecord `json:"history"`
+}
+
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+
+	rss, updated := currentFeed()
+	status := Status{
+		Version:   buildVersion(),
+		FeedItems: len(rss.Channel.Item),
+	}
+	if !updated.IsZero() {
+		status.FeedUpdated = &updated
+	}
+
+	refreshStatus.Lock()
+	status.ConsecutiveFailures = refreshStatus.consecutiveFailures
+	status.History = append([]FetchRecord{}, refreshStatus.history...)
+	if !refreshStatus.nextRefresh.IsZero() {
+		next := refreshStatus.nextRefresh
+		status.NextRefresh = &next
+	}
+	refreshStatus.Unlock()
+
+	if len(status.History) > 0 {
+		last := status.History[len(status.History)-1]
+		status.LastFetch = &last
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", " ")
+	encoder.Encode(status)
+}
+
+type RefreshResult struct {
+	Ok         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	Items      int    `json:"items"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Refresh now rather than waiting for the next scheduled one
+func serveRefresh(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The fetch is shared with the schedule, a client going away mustn't
+	// cut it short and count as a failure
+	start := time.Now()
+	rss, err := refresh(context.WithoutCancel(r.Context()))
+	result := RefreshResult{
+		Ok:         err == nil,
+		Items:      len(rss.Channel.Item),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	var deferred *DeferredError
+	if errors.As(err, &deferred) {
+		result.Error = err.Error()
+		w.Header().Set("Retry-After", strconv.Itoa(int(deferred.Wait.Seconds())+1))
+		w.WriteHeader(http.StatusTooManyRequests)
+	} else if err != nil {
+		result.Error = err.Error()
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(result)
+}
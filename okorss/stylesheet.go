@@ -0,0 +1,58 @@
+package okorss
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Feed readers ignore the stylesheet, browsers render the feed with it
+// as a page instead of showing raw XML
+const feedStylesheet = `<?xml version="1.0" encoding="UTF-8"?>
+<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform" xmlns:atom="http://www.w3.org/2005/Atom">
+<xsl:output method="html" encoding="UTF-8" indent="yes"/>
+<xsl:template match="/rss/channel">
+<html lang="pl">
+<head>
+<meta charset="utf-8"/>
+<meta name="viewport" content="width=device-width, initial-scale=1"/>
+<title><xsl:value-of select="title"/> – kanał RSS</title>
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: auto; padding: 0 16px; color: #222; }
+.about { background: #f3f3f3; padding: 12px 16px; border-radius: 6px; }
+.about code { word-break: break-all; }
+.item { display: flex; gap: 16px; margin: 24px 0; }
+.item img { width: 160px; height: 100px; object-fit: cover; flex-shrink: 0; }
+.item h2 { font-size: 1.1em; margin: 0 0 4px; }
+.item small { color: #666; }
+</style>
+</head>
+<body>
+<h1><xsl:value-of select="title"/></h1>
+<div class="about">
+<p><strong>To jest kanał RSS.</strong> Skopiuj jego adres do czytnika kanałów, np. Feedly, Inoreader, NetNewsWire lub Thunderbird, aby otrzymywać nowe artykuły automatycznie.</p>
+<p>Adres kanału: <code><xsl:value-of select="atom:link[@rel='self']/@href"/></code></p>
+</div>
+<xsl:for-each select="item">
+<div class="item">
+<xsl:if test="enclosure/@url">
+<a href="{link}"><img src="{enclosure/@url}" alt="" loading="lazy"/></a>
+</xsl:if>
+<div>
+<h2><a href="{link}"><xsl:value-of select="title"/></a></h2>
+<small><xsl:value-of select="pubDate"/></small>
+</div>
+</div>
+</xsl:for-each>
+</body>
+</html>
+</xsl:template>
+</xsl:stylesheet>
+`
+
+// Served from the top directory, next to the main feed
+const feedStylesheetPath = "feed.xsl"
+
+func serveStylesheet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xsl; charset=utf-8")
+	fmt.Fprint(w, feedStylesheet)
+}
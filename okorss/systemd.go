@@ -0,0 +1,93 @@
+package okorss
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Send a state change to systemd, see sd_notify(3). Does nothing when not
+// run by systemd with Type=notify
+func sdNotify(state string) error {
+
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// Abstract namespace sockets are given with a leading @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+var notifyReadyOnce sync.Once
+
+// Tell systemd startup finished, once the first feed is there to serve
+func notifyReady() {
+	notifyReadyOnce.Do(func() {
+		err := sdNotify("READY=1")
+		if err != nil {
+			slog.Warn("Error while notifying systemd", "error", err)
+		}
+	})
+}
+
+// Watchdog interval requested by the unit's WatchdogSec, zero without one
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	pid, err := strconv.Atoi(os.Getenv("WATCHDOG_PID"))
+	if err == nil && pid != os.Getpid() {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Scheduled refresh overdue by this much means the refresher loop hung,
+// as a fetch without response blocks it for good
+const refreshStallTimeout = 15 * time.Minute
+
+// Ping the systemd watchdog at half its interval while the refresher loop
+// keeps running, so systemd restarts an instance that hung. An upstream
+// outage doesn't stop the pings, staleness is left to /ready and
+// healthcheck. Before the first feed, TimeoutStartSec applies instead
+func watchdog(ctx context.Context, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+	defer reportPanic()
+
+	interval := watchdogInterval()
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			sdNotify("STOPPING=1")
+			return
+		case now := <-ticker.C:
+			next := refreshStatusNext()
+			if !next.IsZero() && now.Sub(next) > refreshStallTimeout {
+				slog.Warn("Refresh is overdue, withholding watchdog ping", "scheduled", next)
+				continue
+			}
+			err := sdNotify("WATCHDOG=1")
+			if err != nil {
+				slog.Warn("Error while notifying systemd", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package okorss
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// Telegram allows about 20 messages per minute in a channel
+const telegramDefaultDelay = Duration(3 * time.Second)
+
+const telegramDefaultTemplate = `<b>{{.Title}}</b>
+
+{{.Link}}`
+
+type TelegramConfig struct {
+	Enabled     bool     `json:"enabled"`
+	ApiUrl      string   `json:"api_url"`
+	BotToken    string   `json:"bot_token"`
+	ChatID      string   `json:"chat_id"`
+	Template    string   `json:"template"`
+	Delay       Duration `json:"delay"`
+	NoThumbnail bool     `json:"no_thumbnail"`
+}
+
+type TelegramNotifier struct {
+	config   TelegramConfig
+	template *template.Template
+}
+
+func newTelegramNotifier(config TelegramConfig) (*TelegramNotifier, error) {
+
+	if config.ApiUrl == "" {
+		config.ApiUrl = "https://api.telegram.org"
+	}
+	if config.Template == "" {
+		config.Template = telegramDefaultTemplate
+	}
+	if config.Delay == 0 {
+		config.Delay = telegramDefaultDelay
+	}
+
+	// Messages use Telegram's HTML formatting, so let html/template escape
+	// article fields
+	parsed, err := template.New("telegram").Parse(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TelegramNotifier{config: config, template: parsed}, nil
+}
+
+func (telegram *TelegramNotifier) Name() string {
+	return "Telegram " + telegram.config.ChatID
+}
+
+// Send a message per article. One failing doesn't hold up the rest of
+// the batch
+func (telegram *TelegramNotifier) Notify(nodes []Node) error {
+
+	failed := 0
+	for i, node := range nodes {
+		if i > 0 {
+			time.Sleep(time.Duration(telegram.config.Delay))
+		}
+		err := telegram.send(node)
+		if err != nil {
+			slog.Warn("Error while sending Telegram message", "id", node.ID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed", failed, len(nodes))
+	}
+	return nil
+}
+
+func (telegram *TelegramNotifier) send(node Node) error {
+
+	item := newNotifyItem(node)
+	var text bytes.Buffer
+	err := telegram.template.Execute(&text, item)
+	if err != nil {
+		return err
+	}
+
+	// Photo captions are limited to 1024 characters, longer messages go
+	// without thumbnail, as do articles without a picture
+	method := "sendMessage"
+	payload := map[string]interface{}{
+		"chat_id":    telegram.config.ChatID,
+		"parse_mode": "HTML",
+	}
+	if node.Image.Url != "" && !telegram.config.NoThumbnail && len([]rune(text.String())) <= 1024 {
+		method = "sendPhoto"
+		payload["photo"] = item.Image
+		payload["caption"] = text.String()
+	} else {
+		payload["text"] = text.String()
+	}
+
+	endpoint := telegram.config.ApiUrl + "/bot" + telegram.config.BotToken + "/" + method
+	err = retry(3, 5*time.Second, func() error {
+		return sendJson("POST", endpoint, nil, payload, nil)
+	})
+	// The URL carries the bot token, errors end up in logs and Sentry
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return fmt.Errorf("%s %s: %w", urlError.Op, method, urlError.Err)
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package okorss
+
+import (
+	"log/slog"
+	"strings"
+	"text/template"
+)
+
+// Parsed title_template from config, nil to keep titles as they are
+var titleTemplate *template.Template
+
+// Helpers available in title templates, e.g.
+// "[OKO] {{trimSuffix .Title \" | OKO.press\"}}{{with .Category}} ({{.}}){{end}}"
+var titleFuncs = template.FuncMap{
+	"trimPrefix": func(text string, prefix string) string { return strings.TrimPrefix(text, prefix) },
+	"trimSuffix": func(text string, suffix string) string { return strings.TrimSuffix(text, suffix) },
+	"replace":    func(text string, old string, new string) string { return strings.ReplaceAll(text, old, new) },
+	"trim":       strings.TrimSpace,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"join":       strings.Join,
+}
+
+func setupTitleTemplate(source string) error {
+	if source == "" {
+		return nil
+	}
+	parsed, err := template.New("title").Funcs(titleFuncs).Parse(source)
+	if err != nil {
+		return err
+	}
+	titleTemplate = parsed
+	return nil
+}
+
+// Item title after the configured template, the original one if the
+// template fails for this item
+func itemTitle(node Node) string {
+
+	if titleTemplate == nil {
+		return node.Title
+	}
+
+	var categories []string
+	for _, category := range node.Categories {
+		categories = append(categories, category.Name)
+	}
+	category := ""
+	if len(categories) > 0 {
+		category = categories[0]
+	}
+
+	title := getBuffer()
+	defer putBuffer(title)
+	err := titleTemplate.Execute(title, map[string]interface{}{
+		"Title":      node.Title,
+		"Category":   category,
+		"Categories": categories,
+		"Slug":       node.SeoFields.Slug,
+		"Published":  nodeTime(node),
+	})
+	if err != nil {
+		slog.Warn("Error while applying title template", "id", node.ID, "error", err)
+		return node.Title
+	}
+	return strings.TrimSpace(title.String())
+}
@@ -0,0 +1,228 @@
+//go:build !windows
+
+package okorss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Environment telling a new process which descriptors it inherited from
+// the one it replaces, listeners as e.g. "http:3,redirect:4"
+const (
+	upgradeListenerEnv = "OKO_RSS_LISTENER_FDS"
+	upgradeReadyEnv    = "OKO_RSS_READY_FD"
+)
+
+// How long the new process has to start serving before it's given up on
+const upgradeTimeout = time.Minute
+
+// Listeners currently served by name, all handed over on upgrade, and the
+// descriptors of ones passed by the previous process
+var upgradeListeners struct {
+	sync.Mutex
+	listeners map[string]net.Listener
+	inherited map[string]int
+}
+
+func setUpgradeListener(name string, listener net.Listener) {
+	upgradeListeners.Lock()
+	defer upgradeListeners.Unlock()
+	if upgradeListeners.listeners == nil {
+		upgradeListeners.listeners = make(map[string]net.Listener)
+	}
+	upgradeListeners.listeners[name] = listener
+}
+
+// Listener of the name passed by the process this one replaces, nil
+// otherwise
+func inheritedListener(name string) (net.Listener, error) {
+
+	upgradeListeners.Lock()
+	defer upgradeListeners.Unlock()
+
+	if upgradeListeners.inherited == nil {
+		upgradeListeners.inherited = make(map[string]int)
+		for _, entry := range strings.Split(os.Getenv(upgradeListenerEnv), ",") {
+			entryName, value, _ := strings.Cut(entry, ":")
+			fd, err := strconv.Atoi(value)
+			if err == nil {
+				upgradeListeners.inherited[entryName] = fd
+			}
+		}
+		os.Unsetenv(upgradeListenerEnv)
+	}
+	fd, ok := upgradeListeners.inherited[name]
+	if !ok {
+		return nil, nil
+	}
+	delete(upgradeListeners.inherited, name)
+
+	file := os.NewFile(uintptr(fd), "inherited "+name)
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// Tell the process being replaced that this one is serving now
+func notifyUpgradeReady() {
+
+	fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnv))
+	if err != nil {
+		return
+	}
+	os.Unsetenv(upgradeReadyEnv)
+
+	file := os.NewFile(uintptr(fd), "ready")
+	file.Write([]byte{1})
+	file.Close()
+
+	// Under systemd, with NotifyAccess=all, this is the main process now
+	sdNotify("MAINPID=" + strconv.Itoa(os.Getpid()))
+}
+
+// Descriptors of the listeners for the new process, in order of name
+func upgradeFiles() ([]*os.File, []string, error) {
+
+	upgradeListeners.Lock()
+	defer upgradeListeners.Unlock()
+
+	var names []string
+	for name := range upgradeListeners.listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*os.File
+	for _, name := range names {
+		listener := upgradeListeners.listeners[name]
+		filer, ok := listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("%s listener can't be handed over", name)
+		}
+		file, err := filer.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, err
+		}
+		files = append(files, file)
+	}
+	// Otherwise shutting down here would remove the sockets from under it
+	for _, listener := range upgradeListeners.listeners {
+		if unixListener, ok := listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+	return files, names, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// Start the binary again, possibly replaced by a new version, with the
+// listeners, and wait until it serves. The config has to be read again,
+// which stdin can't be
+func upgrade(configPath string) error {
+
+	if configPath == "-" {
+		return errors.New("config was read from stdin, restart instead")
+	}
+	files, names, err := upgradeFiles()
+	if err != nil {
+		return err
+	}
+	defer closeFiles(files)
+
+	// Descriptors 0 to 2 are stdio, ExtraFiles start at 3
+	var fds []string
+	for i, name := range names {
+		fds = append(fds, name+":"+strconv.Itoa(3+i))
+	}
+
+	readyRead, readyWrite, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyRead.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		readyWrite.Close()
+		return err
+	}
+	command := exec.Command(executable, os.Args[1:]...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.ExtraFiles = append(files, readyWrite)
+	command.Env = append(os.Environ(), upgradeListenerEnv+"="+strings.Join(fds, ","), upgradeReadyEnv+"="+strconv.Itoa(3+len(files)))
+	err = command.Start()
+	readyWrite.Close()
+	if err != nil {
+		return err
+	}
+
+	// Nothing is read when the new process exits or can't start serving
+	ready := make(chan bool, 1)
+	go func() {
+		buffer := make([]byte, 1)
+		n, _ := readyRead.Read(buffer)
+		ready <- n == 1
+	}()
+	select {
+	case ok := <-ready:
+		if !ok {
+			command.Wait()
+			return errors.New("new process exited before serving")
+		}
+	case <-time.After(upgradeTimeout):
+		command.Process.Kill()
+		command.Wait()
+		return fmt.Errorf("new process didn't start serving within %s", upgradeTimeout)
+	}
+
+	go command.Process.Release()
+	slog.Info("Handed over to new process", "pid", command.Process.Pid)
+	return nil
+}
+
+// On SIGUSR2 start the binary anew and hand the listeners over, then shut
+// down gracefully, so deploys don't drop connections. When the new
+// process fails this one carries on
+func handleUpgrades(ctx context.Context, shutdown context.CancelFunc, configPath string) {
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			slog.Info("Upgrading to new process")
+			err := upgrade(configPath)
+			if err != nil {
+				slog.Error("Error while upgrading, keeping this process", "error", err)
+				continue
+			}
+			shutdown()
+			return
+		}
+	}
+}
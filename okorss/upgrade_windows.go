@@ -0,0 +1,17 @@
+package okorss
+
+import (
+	"context"
+	"net"
+)
+
+// In-place upgrades rely on Unix signals and descriptor passing
+func setUpgradeListener(name string, listener net.Listener) {}
+
+func inheritedListener(name string) (net.Listener, error) {
+	return nil, nil
+}
+
+func notifyUpgradeReady() {}
+
+func handleUpgrades(ctx context.Context, shutdown context.CancelFunc, configPath string) {}
@@ -0,0 +1,208 @@
+package okorss
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Article field the feed relies on, by its path in a node and JSON type
+type expectedField struct {
+	Path string
+	Type gjson.Type
+	// Null is fine, e.g. articles without a picture
+	Nullable bool
+}
+
+var expectedNodeFields = []expectedField{
+	{Path: "id", Type: gjson.String},
+	{Path: "title", Type: gjson.String},
+	{Path: "publish_at", Type: gjson.String},
+	{Path: "seo_fields.slug", Type: gjson.String},
+	{Path: "featured_image.original_url", Type: gjson.String, Nullable: true},
+}
+
+// Fields empty in every article, which almost always means upstream renamed
+// or moved them rather than every article lacking one. Null is taken as a
+// value for nullable fields
+func driftedFields(nodes []Node) []string {
+
+	var drifted []string
+	for _, field := range expectedNodeFields {
+		present := false
+		for _, node := range nodes {
+			if gjson.GetBytes(node.Raw, field.Path).String() != "" || field.Nullable && explicitNull(node.Raw, field.Path) {
+				present = true
+				break
+			}
+		}
+		if !present {
+			drifted = append(drifted, field.Path)
+		}
+	}
+	return drifted
+}
+
+// Whether the field or an object it's nested in is null, rather than missing
+func explicitNull(data []byte, path string) bool {
+	for i := range path {
+		if path[i] == '.' && gjson.GetBytes(data, path[:i]).Type == gjson.Null && gjson.GetBytes(data, path[:i]).Exists() {
+			return true
+		}
+	}
+	value := gjson.GetBytes(data, path)
+	return value.Exists() && value.Type == gjson.Null
+}
+
+func warnSchemaDrift(nodes []Node) {
+	if len(nodes) == 0 {
+		return
+	}
+	drifted := driftedFields(nodes)
+	if len(drifted) > 0 {
+		slog.Warn("Fields empty in every article, upstream schema may have changed", "fields", drifted, "items", len(nodes))
+	}
+}
+
+func jsonTypeName(value gjson.Result) string {
+	switch {
+	case !value.Exists():
+		return "missing"
+	case value.IsArray():
+		return "array"
+	case value.IsObject():
+		return "object"
+	case value.Type == gjson.True || value.Type == gjson.False:
+		return "boolean"
+	}
+	return strings.ToLower(value.Type.String())
+}
+
+// Problems found with the shape of an OKO.press API response, with a
+// line per expected field written to report
+func validateResponse(body []byte, report *tabwriter.Writer) int {
+
+	problems := 0
+	if !gjson.ValidBytes(body) {
+		fmt.Fprintf(report, "response\tnot JSON\t%s\n", responseSnippet(body))
+		return 1
+	}
+	for _, graphqlError := range gjson.GetBytes(body, "errors").Array() {
+		fmt.Fprintf(report, "errors\tGraphQL error\t%s\n", graphqlError.Get("message").String())
+		problems++
+	}
+
+	nodes := gjson.GetBytes(body, "data.nodes")
+	if !nodes.IsArray() {
+		fmt.Fprintf(report, "data.nodes\tMISSING\texpected array, got %s\n", jsonTypeName(nodes))
+		return problems + 1
+	}
+	count := len(nodes.Array())
+	fmt.Fprintf(report, "data.nodes\tok\t%d articles\n", count)
+	if count == 0 {
+		fmt.Fprintf(report, "data.nodes\tEMPTY\tno articles to check fields of\n")
+		return problems + 1
+	}
+
+	for _, field := range expectedNodeFields {
+		missing, nulls, unparsed := 0, 0, 0
+		otherTypes := make(map[string]int)
+		for _, node := range nodes.Array() {
+			value := node.Get(field.Path)
+			parent, _, nested := strings.Cut(field.Path, ".")
+			switch {
+			case nested && node.Get(parent).Type == gjson.Null:
+				nulls++
+			case !value.Exists():
+				missing++
+			case value.Type == gjson.Null:
+				nulls++
+			case value.Type != field.Type || value.IsArray() || value.IsObject():
+				otherTypes[jsonTypeName(value)]++
+			case field.Path == "publish_at":
+				_, err := time.Parse("2006-01-02T15:04:05", value.String())
+				if err != nil {
+					unparsed++
+				}
+			}
+		}
+
+		var notes []string
+		status := "ok"
+		if missing > 0 {
+			status = "MISSING"
+			notes = append(notes, fmt.Sprintf("missing in %d of %d", missing, count))
+		}
+		if nulls > 0 {
+			if !field.Nullable {
+				status = "MISSING"
+			}
+			notes = append(notes, fmt.Sprintf("null in %d of %d", nulls, count))
+		}
+		for name, times := range otherTypes {
+			status = "CHANGED TYPE"
+			notes = append(notes, fmt.Sprintf("%s instead of %s in %d of %d", name, strings.ToLower(field.Type.String()), times, count))
+		}
+		if unparsed > 0 {
+			status = "CHANGED FORMAT"
+			notes = append(notes, fmt.Sprintf("not YYYY-MM-DDTHH:MM:SS in %d of %d", unparsed, count))
+		}
+		if status != "ok" {
+			problems++
+		}
+		fmt.Fprintf(report, "%s\t%s\t%s\n", field.Path, status, strings.Join(notes, ", "))
+	}
+	return problems
+}
+
+// Fetch the configured endpoint once and report whether its response still
+// has the fields the feed is built from. Exits with 1 when it doesn't
+func validateCommand(args []string) {
+
+	var configPath, url string
+
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "NO_CONFIG", "config file path")
+	flags.StringVar(&configPath, "config", "NO_CONFIG", "config file path")
+	flags.StringVar(&url, "url", "", "endpoint to check instead of the configured one")
+	flags.Parse(args)
+
+	if configPath == "NO_CONFIG" && url == "" {
+		fmt.Printf("Please specify config path!")
+		return
+	}
+	if configPath != "NO_CONFIG" {
+		loadConfig(configPath)
+	}
+	if url == "" {
+		if config.Source.Type != "" && config.Source.Type != "okopress" {
+			fatal("Only the okopress source can be validated", "type", config.Source.Type)
+		}
+		source, err := newOkoPressSource(config)
+		if err != nil {
+			fatal("Error while setting up source", "error", err)
+		}
+		url = source.(OkoPressSource).Url
+	}
+
+	body, err := fetchBody(context.Background(), url, nil)
+	if err != nil {
+		fatal("Error while fetching", "url", url, "error", err)
+	}
+
+	report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(report, "FIELD\tSTATUS\tDETAILS\n")
+	problems := validateResponse(body, report)
+	report.Flush()
+	if problems > 0 {
+		fmt.Printf("\n%d problem(s) found\n", problems)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,75 @@
+package okorss
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Receivers can verify payloads by computing HMAC-SHA256 of the request
+// body with the shared secret and comparing it with this header
+const webhookSignatureHeader = "X-Oko-Rss-Signature"
+
+type WebhookConfig struct {
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+type WebhookItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Published time.Time `json:"published"`
+	Image     string    `json:"image"`
+}
+
+type WebhookPayload struct {
+	Event string        `json:"event"`
+	Items []WebhookItem `json:"items"`
+}
+
+func (webhook WebhookConfig) Name() string {
+	return "webhook " + webhook.Url
+}
+
+func (webhook WebhookConfig) Notify(nodes []Node) error {
+
+	payload := WebhookPayload{Event: "new_items"}
+	for _, node := range nodes {
+		payload.Items = append(payload.Items, WebhookItem{
+			ID:        node.ID,
+			Title:     node.Title,
+			Link:      nodeLink(node),
+			Published: nodeTime(node),
+			Image:     nodeImage(node),
+		})
+	}
+	return webhook.post(payload)
+}
+
+// Send JSON payload, signed when a secret is set
+func (webhook WebhookConfig) post(payload interface{}) error {
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return retry(3, 5*time.Second, func() error {
+		request, err := http.NewRequest("POST", webhook.Url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		if webhook.Secret != "" {
+			signature := hmac.New(sha256.New, []byte(webhook.Secret))
+			signature.Write(body)
+			request.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(signature.Sum(nil)))
+		}
+		return doRequest(request, nil)
+	})
+}
@@ -0,0 +1,232 @@
+package okorss
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type WebSocketConfig struct {
+	Origins    []string `json:"origins"`
+	MaxClients int      `json:"max_clients"`
+}
+
+// Which new articles a connection wants, all when empty. Categories match
+// by name or slug, keywords anywhere in the title or lead, ignoring case
+type WebSocketFilter struct {
+	Categories []string `json:"categories"`
+	Keywords   []string `json:"keywords"`
+}
+
+func (filter WebSocketFilter) match(node Node) bool {
+
+	if len(filter.Categories) > 0 && !inAnyCategory(node, filter.Categories) {
+		return false
+	}
+	if len(filter.Keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(node.Title + " " + node.Lead)
+	for _, keyword := range filter.Keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Message pushed to clients
+type WebSocketMessage struct {
+	Type  string       `json:"type"`
+	Items []NotifyItem `json:"items"`
+}
+
+const (
+	webSocketPingInterval = 30 * time.Second
+	webSocketPongTimeout  = 60 * time.Second
+	webSocketWriteTimeout = 10 * time.Second
+	// Batches waiting per client, beyond this the client is disconnected
+	webSocketQueueSize = 16
+)
+
+type webSocketClient struct {
+	conn   *websocket.Conn
+	queue  chan []Node
+	mutex  sync.Mutex
+	filter WebSocketFilter
+}
+
+var webSockets struct {
+	sync.Mutex
+	clients map[*webSocketClient]bool
+}
+
+var webSocketUpgrader = websocket.Upgrader{CheckOrigin: checkWebSocketOrigin}
+
+// Without configured origins browsers may connect only from this host, as
+// by default. Clients other than browsers send no Origin and are let in
+func checkWebSocketOrigin(r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(config.WebSocket.Origins) == 0 {
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+	for _, allowed := range config.WebSocket.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func maxWebSocketClients() int {
+	if config.WebSocket.MaxClients > 0 {
+		return config.WebSocket.MaxClients
+	}
+	return 1000
+}
+
+// Push new articles as they are discovered. The initial filter comes from
+// category and q query parameters, a filter sent as JSON replaces it
+func serveWebSocket(w http.ResponseWriter, r *http.Request) {
+
+	webSockets.Lock()
+	full := len(webSockets.clients) >= maxWebSocketClients()
+	webSockets.Unlock()
+	if full {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := webSocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has responded already
+		return
+	}
+
+	client := &webSocketClient{
+		conn:  conn,
+		queue: make(chan []Node, webSocketQueueSize),
+		filter: WebSocketFilter{
+			Categories: r.URL.Query()["category"],
+			Keywords:   r.URL.Query()["q"],
+		},
+	}
+	webSockets.Lock()
+	if webSockets.clients == nil {
+		webSockets.clients = make(map[*webSocketClient]bool)
+	}
+	webSockets.clients[client] = true
+	webSockets.Unlock()
+	slog.Debug("WebSocket client connected", "client", clientIP(r))
+
+	go client.write()
+	client.read()
+
+	webSockets.Lock()
+	delete(webSockets.clients, client)
+	webSockets.Unlock()
+	close(client.queue)
+	slog.Debug("WebSocket client disconnected", "client", clientIP(r))
+}
+
+// Take filter updates until the client goes away or stops answering pings
+func (client *webSocketClient) read() {
+
+	client.conn.SetReadLimit(64 << 10)
+	client.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	})
+
+	for {
+		_, data, err := client.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var filter WebSocketFilter
+		err = json.Unmarshal(data, &filter)
+		if err != nil {
+			slog.Debug("Ignoring malformed WebSocket filter", "error", err)
+			continue
+		}
+		client.mutex.Lock()
+		client.filter = filter
+		client.mutex.Unlock()
+	}
+}
+
+// Send matching articles and keep the connection alive with pings
+func (client *webSocketClient) write() {
+
+	ticker := time.NewTicker(webSocketPingInterval)
+	defer ticker.Stop()
+	defer client.conn.Close()
+
+	for {
+		select {
+		case nodes, ok := <-client.queue:
+			if !ok {
+				client.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(webSocketWriteTimeout))
+				return
+			}
+			message := WebSocketMessage{Type: "items"}
+			client.mutex.Lock()
+			for _, node := range nodes {
+				if client.filter.match(node) {
+					message.Items = append(message.Items, newNotifyItem(node))
+				}
+			}
+			client.mutex.Unlock()
+			if len(message.Items) == 0 {
+				continue
+			}
+			client.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+			err := client.conn.WriteJSON(message)
+			if err != nil {
+				return
+			}
+		case <-ticker.C:
+			err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Pass new articles to every connected client. A client too slow to keep
+// up is dropped rather than holding back the others
+func pushWebSockets(nodes []Node) {
+
+	webSockets.Lock()
+	defer webSockets.Unlock()
+	for client := range webSockets.clients {
+		select {
+		case client.queue <- nodes:
+		default:
+			slog.Warn("WebSocket client is too slow, disconnecting")
+			client.conn.Close()
+		}
+	}
+}
+
+// Hijacked connections aren't closed by http.Server.Shutdown
+func closeWebSockets() {
+	webSockets.Lock()
+	defer webSockets.Unlock()
+	for client := range webSockets.clients {
+		client.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(time.Second))
+		client.conn.Close()
+	}
+}
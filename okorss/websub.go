@@ -0,0 +1,354 @@
+package okorss
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hub for the feeds' own WebSub subscribers, see
+// https://www.w3.org/TR/websub/
+type WebSubConfig struct {
+	Enabled bool `json:"enabled"`
+	// Longest subscription granted, 10 days by default
+	MaxLease Duration `json:"max_lease"`
+}
+
+const webSubHubPath = "/hub"
+
+const maxWebSubSubscriptions = 1000
+
+// Verifications in flight per client address, each one makes a request to
+// the callback
+const maxPendingWebSubVerifications = 5
+
+var webSubVerifications sync.WaitGroup
+
+var webSubPending = struct {
+	sync.Mutex
+	byClient map[string]int
+}{byClient: make(map[string]int)}
+
+type webSubSubscription struct {
+	topic    string
+	path     string
+	hub      string
+	callback string
+	secret   string
+	expires  time.Time
+}
+
+var webSub struct {
+	sync.Mutex
+	subscriptions map[string]webSubSubscription
+	// Hash of the items last distributed per feed path
+	distributed map[string]string
+}
+
+func webSubMaxLease() time.Duration {
+	if config.WebSub.MaxLease > 0 {
+		return time.Duration(config.WebSub.MaxLease)
+	}
+	return 10 * 24 * time.Hour
+}
+
+// Advertise the hub in the feed and the Link header, for feeds the hub
+// distributes
+func addHubLinks(w http.ResponseWriter, r *http.Request, rss *RssFeed) {
+
+	if !config.WebSub.Enabled {
+		return
+	}
+	base := requestBaseUrl(r)
+	hub := base + webSubHubPath
+	self := base + r.URL.Path
+	rss.Channel.AtomLink = append(rss.Channel.AtomLink, AtomLink{Rel: "hub", Href: hub})
+	w.Header().Add("Link", "<"+hub+">; rel=\"hub\"")
+	w.Header().Add("Link", "<"+self+">; rel=\"self\"")
+}
+
+// Current feed at a path, for the feeds which change with refreshes. Not
+// ok for other paths
+func topicFeed(path string) (RssFeed, time.Time, bool) {
+
+	if path == mainFeedPath {
+		rss, updated := currentFeed()
+		return rss, updated, true
+	}
+	for _, section := range sectionFeeds {
+		if section.config.Path == path {
+			section.RLock()
+			defer section.RUnlock()
+			return section.rss, section.updated, true
+		}
+	}
+	return RssFeed{}, time.Time{}, false
+}
+
+// Subscription and unsubscription requests. Intent is verified with the
+// subscriber afterwards, the request is only accepted here
+func serveWebSubHub(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode := r.PostForm.Get("hub.mode")
+	if mode != "subscribe" && mode != "unsubscribe" {
+		http.Error(w, "hub.mode must be subscribe or unsubscribe", http.StatusBadRequest)
+		return
+	}
+	callback, err := url.Parse(r.PostForm.Get("hub.callback"))
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		http.Error(w, "hub.callback must be an HTTP URL", http.StatusBadRequest)
+		return
+	}
+	err = checkCallbackHost(r.Context(), callback.Hostname())
+	if err != nil {
+		slog.Info("Rejected WebSub callback", "client", clientIP(r), "callback", callback.String(), "error", err)
+		http.Error(w, "hub.callback is not reachable from here", http.StatusBadRequest)
+		return
+	}
+	topic := r.PostForm.Get("hub.topic")
+	topicUrl, err := url.Parse(topic)
+	if err != nil {
+		http.Error(w, "invalid hub.topic", http.StatusBadRequest)
+		return
+	}
+	topicPath := strings.TrimPrefix(topicUrl.Path, requestBasePath(r))
+	if _, _, ok := topicFeed(topicPath); !ok {
+		http.Error(w, "hub.topic is not a feed served here", http.StatusBadRequest)
+		return
+	}
+	secret := r.PostForm.Get("hub.secret")
+	if len(secret) > 200 {
+		http.Error(w, "hub.secret is longer than 200 bytes", http.StatusBadRequest)
+		return
+	}
+	lease := webSubMaxLease()
+	if value := r.PostForm.Get("hub.lease_seconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "invalid hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+		lease = min(lease, time.Duration(seconds)*time.Second)
+	}
+
+	subscription := webSubSubscription{
+		topic:    topic,
+		path:     topicPath,
+		hub:      requestBaseUrl(r) + webSubHubPath,
+		callback: callback.String(),
+		secret:   secret,
+	}
+	client := clientIP(r)
+	webSubPending.Lock()
+	if webSubPending.byClient[client] >= maxPendingWebSubVerifications {
+		webSubPending.Unlock()
+		http.Error(w, "too many pending verifications", http.StatusTooManyRequests)
+		return
+	}
+	webSubPending.byClient[client]++
+	webSubPending.Unlock()
+
+	webSubVerifications.Add(1)
+	go func() {
+		defer webSubVerifications.Done()
+		defer func() {
+			webSubPending.Lock()
+			webSubPending.byClient[client]--
+			if webSubPending.byClient[client] == 0 {
+				delete(webSubPending.byClient, client)
+			}
+			webSubPending.Unlock()
+		}()
+		verifyWebSubIntent(mode, subscription, lease)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Confirm the subscriber asked for it before (un)subscribing, by having it
+// echo a challenge
+func verifyWebSubIntent(mode string, subscription webSubSubscription, lease time.Duration) {
+
+	defer reportPanic()
+
+	challenge := make([]byte, 16)
+	rand.Read(challenge)
+	expected := hex.EncodeToString(challenge)
+
+	verification, _ := url.Parse(subscription.callback)
+	query := verification.Query()
+	query.Set("hub.mode", mode)
+	query.Set("hub.topic", subscription.topic)
+	query.Set("hub.challenge", expected)
+	if mode == "subscribe" {
+		query.Set("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+	verification.RawQuery = query.Encode()
+
+	err := func() error {
+		response, err := callbackClient.Get(verification.String())
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+		if err != nil {
+			return err
+		}
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return fmt.Errorf("bad HTTP status: %s", response.Status)
+		}
+		if string(body) != expected {
+			return fmt.Errorf("challenge not echoed back")
+		}
+		return nil
+	}()
+	if err != nil {
+		slog.Info("WebSub intent not verified", "mode", mode, "topic", subscription.topic, "callback", subscription.callback, "error", err)
+		return
+	}
+
+	webSub.Lock()
+	defer webSub.Unlock()
+	if webSub.subscriptions == nil {
+		webSub.subscriptions = make(map[string]webSubSubscription)
+	}
+	key := subscription.topic + " " + subscription.callback
+	if mode == "unsubscribe" {
+		delete(webSub.subscriptions, key)
+		slog.Info("WebSub unsubscribed", "topic", subscription.topic, "callback", subscription.callback)
+		return
+	}
+	pruneWebSubSubscriptions(time.Now())
+	_, renewal := webSub.subscriptions[key]
+	if !renewal && len(webSub.subscriptions) >= maxWebSubSubscriptions {
+		slog.Warn("Too many WebSub subscriptions, ignoring new one", "callback", subscription.callback)
+		return
+	}
+	subscription.expires = time.Now().Add(lease)
+	webSub.subscriptions[key] = subscription
+	// Only changes from now on are news to the subscriber
+	if webSub.distributed == nil {
+		webSub.distributed = make(map[string]string)
+	}
+	if _, ok := webSub.distributed[subscription.path]; !ok {
+		rss, _, _ := topicFeed(subscription.path)
+		webSub.distributed[subscription.path] = feedItemsHash(rss)
+	}
+	slog.Info("WebSub subscribed", "topic", subscription.topic, "callback", subscription.callback, "lease", lease)
+}
+
+// Caller holds the lock
+func pruneWebSubSubscriptions(now time.Time) {
+	for key, subscription := range webSub.subscriptions {
+		if now.After(subscription.expires) {
+			delete(webSub.subscriptions, key)
+		}
+	}
+}
+
+// Hash of what subscribers care about, the items. The channel carries the
+// refresh time and would change every time
+func feedItemsHash(rss RssFeed) string {
+	hash := sha256.New()
+	xml.NewEncoder(hash).Encode(rss.Channel.Item)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Held while distributing, so slow subscribers don't pile up runs
+var webSubDistributing sync.Mutex
+
+// Send feeds whose items changed since they were last sent to their
+// subscribers. Skipped while the previous run is still going, the next
+// one catches up
+func distributeWebSub() {
+
+	if !config.WebSub.Enabled || !webSubDistributing.TryLock() {
+		return
+	}
+	defer webSubDistributing.Unlock()
+	defer reportPanic()
+
+	webSub.Lock()
+	pruneWebSubSubscriptions(time.Now())
+	if webSub.distributed == nil {
+		webSub.distributed = make(map[string]string)
+	}
+	changed := make(map[string]RssFeed)
+	updates := make(map[string]time.Time)
+	var subscriptions []webSubSubscription
+	for _, subscription := range webSub.subscriptions {
+		rss, updated, _ := topicFeed(subscription.path)
+		if updated.IsZero() {
+			continue
+		}
+		if _, seen := changed[subscription.path]; !seen {
+			hash := feedItemsHash(rss)
+			if webSub.distributed[subscription.path] == hash {
+				continue
+			}
+			changed[subscription.path] = rss
+			updates[subscription.path] = updated
+			webSub.distributed[subscription.path] = hash
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	webSub.Unlock()
+
+	for _, subscription := range subscriptions {
+		err := sendWebSubContent(subscription, changed[subscription.path], updates[subscription.path])
+		if err != nil {
+			slog.Warn("Error while distributing to WebSub subscriber", "topic", subscription.topic, "callback", subscription.callback, "error", err)
+		}
+	}
+}
+
+// Post the full feed, signed with the subscriber's secret when it gave one
+func sendWebSubContent(subscription webSubSubscription, rss RssFeed, updated time.Time) error {
+
+	rss.Channel.AtomLink = []AtomLink{{Rel: "self", Href: subscription.topic}, {Rel: "hub", Href: subscription.hub}}
+	var body bytes.Buffer
+	stylesheet := strings.TrimSuffix(subscription.hub, webSubHubPath) + "/" + feedStylesheetPath
+	err := writeRss(&body, rss, updated, stylesheet)
+	if err != nil {
+		return err
+	}
+
+	return retry(3, 5*time.Second, func() error {
+		request, err := http.NewRequest("POST", subscription.callback, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", feedContentType())
+		request.Header.Add("Link", "<"+subscription.hub+">; rel=\"hub\"")
+		request.Header.Add("Link", "<"+subscription.topic+">; rel=\"self\"")
+		if subscription.secret != "" {
+			mac := hmac.New(sha256.New, []byte(subscription.secret))
+			mac.Write(body.Bytes())
+			request.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		return doRequestWith(callbackClient, request, nil)
+	})
+}
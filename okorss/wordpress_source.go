@@ -0,0 +1,116 @@
+package okorss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Post as returned by the WordPress REST API with _embed
+type WordPressPost struct {
+	ID      int    `json:"id"`
+	DateGmt string `json:"date_gmt"`
+	Link    string `json:"link"`
+	Slug    string `json:"slug"`
+	Title   struct {
+		Rendered string `json:"rendered"`
+	} `json:"title"`
+	Excerpt struct {
+		Rendered string `json:"rendered"`
+	} `json:"excerpt"`
+	Embedded struct {
+		Author []struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		FeaturedMedia []struct {
+			SourceUrl string `json:"source_url"`
+		} `json:"wp:featuredmedia"`
+		Terms [][]struct {
+			Name     string `json:"name"`
+			Slug     string `json:"slug"`
+			Taxonomy string `json:"taxonomy"`
+		} `json:"wp:term"`
+	} `json:"_embedded"`
+}
+
+// Posts of a WordPress site with the REST API enabled, source url being
+// the site address, e.g. "https://example.pl"
+type WordPressSource struct {
+	config SourceConfig
+}
+
+func newWordPressSource(config Config) (Source, error) {
+	source := config.Source
+	if source.Url == "" {
+		return nil, fmt.Errorf("source url is required for the wordpress source")
+	}
+	if source.Limit == 0 {
+		source.Limit = 20
+	}
+	return WordPressSource{config: source}, nil
+}
+
+func (source WordPressSource) Name() string {
+	return "WordPress " + source.config.Url
+}
+
+func (source WordPressSource) Fetch(ctx context.Context) ([]Node, error) {
+
+	query := url.Values{}
+	query.Set("_embed", "author,wp:featuredmedia,wp:term")
+	query.Set("per_page", strconv.Itoa(source.config.Limit))
+	endpoint := strings.TrimSuffix(source.config.Url, "/") + "/wp-json/wp/v2/posts?" + query.Encode()
+
+	body, err := fetchBody(ctx, endpoint, source.config.Headers)
+	if err != nil {
+		return nil, err
+	}
+	var posts []WordPressPost
+	err = json.Unmarshal(body, &posts)
+	if err != nil {
+		return nil, &UpstreamError{StatusCode: http.StatusOK, Snippet: responseSnippet(body), Err: fmt.Errorf("parsing posts: %w", err)}
+	}
+
+	var nodes []Node
+	for _, post := range posts {
+		nodes = append(nodes, post.node())
+	}
+	return nodes, nil
+}
+
+func (post WordPressPost) node() Node {
+
+	var node Node
+	node.ID = strconv.Itoa(post.ID)
+	node.Title = plainText(post.Title.Rendered)
+	node.Lead = plainText(post.Excerpt.Rendered)
+	node.Link = post.Link
+	node.SeoFields.Slug = post.Slug
+
+	// Already in the API time format, but be lenient
+	published, err := time.Parse("2006-01-02T15:04:05", post.DateGmt)
+	if err == nil {
+		node.Published = apiTime(published)
+	}
+
+	if len(post.Embedded.FeaturedMedia) > 0 {
+		node.Image.Url = post.Embedded.FeaturedMedia[0].SourceUrl
+	}
+	for _, author := range post.Embedded.Author {
+		node.Authors = append(node.Authors, Author{Name: author.Name})
+	}
+	for _, terms := range post.Embedded.Terms {
+		for _, term := range terms {
+			if term.Taxonomy == "category" {
+				node.Categories = append(node.Categories, Category{Name: plainText(term.Name), Slug: term.Slug})
+			}
+		}
+	}
+
+	return node
+}